@@ -4,13 +4,30 @@
 package avltree
 
 import (
+	"constraints"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/johan-bolmsjo/gods/v2/iter"
 	"github.com/johan-bolmsjo/gods/v2/list"
 	"github.com/johan-bolmsjo/gods/v2/math"
 )
 
-// Maximum tree height supported by a tree.
+// ErrUnsorted is returned when input that must be sorted is not.
+var ErrUnsorted = errors.New("avltree: input is not sorted")
+
+// Number of associations visited by ApplyContext between context checks.
+const applyContextInterval = 64
+
+// Maximum tree height supported by a tree. It sizes the stack allocated paths
+// used when modifying the tree; iterator paths grow as needed.
 // This is a *large* tree, larger than reasonable.
 const maxTreeHeight = 48
 
@@ -18,13 +35,39 @@ const maxTreeHeight = 48
  * Tree
  *****************************************************************************/
 
+// Assoc is an association between a key and a value.
+type Assoc[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// Outcome reports how an association was stored by UpsertBatch.
+type Outcome int
+
+const (
+	Inserted    Outcome = iota // A new association was inserted
+	Overwritten                // An existing association was overwritten
+)
+
 // Tree is an AVL tree.
 type Tree[K, V any] struct {
 	root        *node[K, V]
 	length      int
-	nodePool    *nodePool[K, V]
+	allocator   Allocator[K, V] // Node allocator, may be nil
+	arena       *nodeArena[K, V]
 	compareKeys math.Comparator[K]
 	iters       list.Node[*Iterator[K, V]]
+	maxSize     int                  // Maximum number of associations if > 0
+	evict       func(K, V) (K, bool) // Eviction victim selector, may be nil
+	evictOldest bool                 // Evict the least recently stamped association
+	onEvict     func(K, V)           // Called with evicted associations, may be nil
+	internKey   func(K) K            // Key canonicalizer, may be nil
+	frozen      bool                 // Modifications are disallowed
+	sequenced   bool                 // Stamp modified nodes with sequence numbers
+	seq         uint64               // Last sequence number
+	rotations   uint64               // Number of rebalancing rotations
+	appendOpt   bool                 // Use the append fast path in add
+	rightmost   *node[K, V]          // Node with the highest key, may be nil
 }
 
 // New creates an AVL tree using the supplied compare function and tree options.
@@ -42,16 +85,213 @@ func New[K, V any](compareKeys math.Comparator[K], options ...TreeOption[K, V])
 	return tree
 }
 
+// NewOrderedDescending creates an AVL tree of keys satisfying
+// constraints.Ordered that are sorted in descending order. Note that the order
+// is reversed for all methods, for example FindLowest returns the association
+// with the highest key and NewIterator advances from high to low key values.
+func NewOrderedDescending[K constraints.Ordered, V any](options ...TreeOption[K, V]) *Tree[K, V] {
+	return New(func(lhs, rhs K) int { return math.CompareOrdered(rhs, lhs) }, options...)
+}
+
+// BuildSorted creates an AVL tree using the supplied compare function and tree
+// options holding associations between keys[i] and values[i]. The keys must be
+// sorted in strictly ascending order. A balanced tree is built directly from
+// the input in O(n) time, except for trees with a maximum size (see
+// WithMaxSize) that are built one association at a time. Panics if the keys
+// are not sorted and unique or if keys and values differ in length.
+func BuildSorted[K, V any](compareKeys math.Comparator[K], keys []K, values []V, options ...TreeOption[K, V]) *Tree[K, V] {
+	if len(keys) != len(values) {
+		panic("avltree: keys and values differ in length")
+	}
+	for i := 1; i < len(keys); i++ {
+		if compareKeys(keys[i-1], keys[i]) >= 0 {
+			panic("avltree: keys are not sorted and unique")
+		}
+	}
+
+	tree := New(compareKeys, options...)
+	if tree.maxSize > 0 {
+		for i := range keys {
+			tree.Add(keys[i], values[i])
+		}
+		return tree
+	}
+
+	nodes := make([]*node[K, V], len(keys))
+	for i, key := range keys {
+		if tree.internKey != nil {
+			key = tree.internKey(key)
+		}
+		n := tree.newNode()
+		n.key, n.value = key, values[i]
+		tree.stamp(n)
+		nodes[i] = n
+	}
+	tree.adoptNodes(nodes, true)
+	return tree
+}
+
+// Create an empty tree with the same compare function and options as tree.
+func (tree *Tree[K, V]) newEmpty() *Tree[K, V] {
+	empty := &Tree[K, V]{
+		allocator:   tree.allocator,
+		arena:       tree.arena.fresh(),
+		compareKeys: tree.compareKeys,
+		maxSize:     tree.maxSize,
+		evict:       tree.evict,
+		evictOldest: tree.evictOldest,
+		onEvict:     tree.onEvict,
+		internKey:   tree.internKey,
+		sequenced:   tree.sequenced,
+		appendOpt:   tree.appendOpt,
+	}
+	empty.iters.InitLinks()
+	return empty
+}
+
 // Add association between key and value to the tree. Any existing association
 // for key is overwritten with key and value.
 func (tree *Tree[K, V]) Add(key K, value V) {
+	tree.add(key, value, true)
+}
+
+// AddReturningOld adds an association between key and value to the tree like
+// Add and returns the value of any overwritten association and true. The zero
+// value of V and false is returned if no association existed for key.
+func (tree *Tree[K, V]) AddReturningOld(key K, value V) (old V, existed bool) {
+	_, old, inserted := tree.add(key, value, true)
+	if inserted {
+		return zeroValue[V]()
+	}
+	return old, true
+}
+
+// AddIfAbsent adds an association between key and value to the tree and returns
+// true if no association for key exist. Otherwise the tree is left unchanged and
+// false is returned. The tree is only descended once.
+func (tree *Tree[K, V]) AddIfAbsent(key K, value V) bool {
+	_, _, inserted := tree.add(key, value, false)
+	return inserted
+}
+
+// GetOrAdd returns the value associated with key and true if an association
+// was found. Otherwise an association between key and value is added and value
+// and false is returned. The tree is only descended once.
+func (tree *Tree[K, V]) GetOrAdd(key K, value V) (V, bool) {
+	node, _, inserted := tree.add(key, value, false)
+	return node.value, !inserted
+}
+
+// Update calls f with the value associated with key and true, or the zero value
+// of V and false if no association was found. The value returned by f is stored
+// for key if f also returns true. Otherwise any association for key is removed.
+// The function must not modify the tree. The tree is descended once, also when
+// an association is added or removed, unless an association must be evicted
+// to make room (see WithMaxSize).
+func (tree *Tree[K, V]) Update(key K, f func(old V, found bool) (V, bool)) {
+	tree.checkMutable()
+	var path nodePath[K, V]
+	node := path.descend(tree.root, func(n *node[K, V]) int {
+		return tree.compareKeys(n.key, key)
+	})
+	if node != nil {
+		if value, keep := f(node.value, true); keep {
+			node.value = value
+			tree.stamp(node)
+		} else {
+			tree.removeAt(node, &path)
+		}
+		return
+	}
+
+	value, keep := f(zeroValue[V]())
+	if !keep {
+		return
+	}
+	if tree.maxSize > 0 && tree.length >= tree.maxSize {
+		// Evicting an association invalidates the path.
+		tree.Add(key, value)
+		return
+	}
+	tree.insertAt(key, value, &path)
+}
+
+// UpsertBatch adds each association of pairs to the tree like Add and returns
+// the outcome for each of them in the order of pairs. The associations are
+// added in ascending key order to improve locality, with associations of equal
+// keys added in the order of pairs so that the last one is stored. Trees with a
+// maximum size (see WithMaxSize) add associations in the order of pairs
+// instead to evict the same associations as a sequence of Add calls would.
+func (tree *Tree[K, V]) UpsertBatch(pairs []Assoc[K, V]) []Outcome {
+	order := make([]int, len(pairs))
+	for i := range order {
+		order[i] = i
+	}
+	if tree.maxSize == 0 {
+		sort.SliceStable(order, func(i, j int) bool {
+			return tree.compareKeys(pairs[order[i]].Key, pairs[order[j]].Key) < 0
+		})
+	}
+
+	outcomes := make([]Outcome, len(pairs))
+	for _, i := range order {
+		if _, _, inserted := tree.add(pairs[i].Key, pairs[i].Value, true); !inserted {
+			outcomes[i] = Overwritten
+		}
+	}
+	return outcomes
+}
+
+// Increment adds delta to the value associated with key and returns the new
+// value. An association between key and delta is added if no association was
+// found. The tree is only descended once.
+func Increment[K any, N constraints.Integer](tree *Tree[K, N], key K, delta N) N {
+	node, _, inserted := tree.add(key, delta, false)
+	if !inserted {
+		node.value += delta
+		tree.stamp(node)
+	}
+	return node.value
+}
+
+// Add association between key and value to the tree unless an association for
+// key exist and overwrite is false. Returns the node holding the association
+// for key, the value it held before and whether it was inserted.
+func (tree *Tree[K, V]) add(key K, value V, overwrite bool) (*node[K, V], V, bool) {
+	var old V
+
+	tree.checkMutable()
+	if tree.internKey != nil {
+		key = tree.internKey(key)
+	}
+	if tree.maxSize > 0 && tree.length >= tree.maxSize {
+		if !tree.Contains(key) {
+			tree.evictFor(key, value)
+		}
+	}
+
 	// Empty tree case
 	if tree.root == nil {
-		tree.root = tree.nodePool.get()
+		tree.root = tree.newNode()
 		tree.root.key = key
 		tree.root.value = value
+		tree.root.size = 1
+		tree.stamp(tree.root)
 		tree.length++
-		return
+		return tree.root, old, true
+	}
+
+	// Keys higher than the highest key are appended along the right spine of
+	// the tree without comparing keys
+	var appending bool
+	if tree.appendOpt {
+		if tree.rightmost == nil {
+			tree.rightmost = tree.root
+			for tree.rightmost.link[directionRight] != nil {
+				tree.rightmost = tree.rightmost.link[directionRight]
+			}
+		}
+		appending = tree.compareKeys(tree.rightmost.key, key) < 0
 	}
 
 	// Set up false tree root to ease maintenance
@@ -59,20 +299,32 @@ func (tree *Tree[K, V]) Add(key K, value V) {
 	t := &head
 	t.link[directionRight] = tree.root
 
-	var dir direction
+	dir := directionRight
 	var s *node[K, V]    // Place to rebalance and parent
 	var p, q *node[K, V] // Iterator and save pointer
 
+	// Directions taken by the search, indexed by depth, so that balance
+	// factors can be updated without comparing keys again
+	var dirs [maxTreeHeight]direction
+	var depth, sDepth int
+
 	// Search down the tree, saving rebalance points
-	for s, p = t.link[directionRight], t.link[directionRight]; ; p = q {
-		cmp := tree.compareKeys(p.key, key)
-		if cmp == 0 {
-			// Update association
-			p.key, p.value = key, value
-			return
+	for s, p = t.link[directionRight], t.link[directionRight]; ; p, depth = q, depth+1 {
+		if !appending {
+			cmp := tree.compareKeys(p.key, key)
+			if cmp == 0 {
+				// Update association
+				old = p.value
+				if overwrite {
+					p.key, p.value = key, value
+					tree.stamp(p)
+				}
+				return p, old, false
+			}
+			dir = directionOfBool(cmp < 0)
 		}
+		dirs[depth] = dir
 
-		dir = directionOfBool(cmp < 0)
 		if q = p.link[dir]; q == nil {
 			break
 		}
@@ -80,25 +332,33 @@ func (tree *Tree[K, V]) Add(key K, value V) {
 		if q.balance != 0 {
 			t = p
 			s = q
+			sDepth = depth + 1
 		}
 	}
 
-	q = tree.nodePool.get()
-	q.key, q.value = key, value
-	p.link[dir] = q
+	n := tree.newNode()
+	n.key, n.value = key, value
+	n.size = 1
+	tree.stamp(n)
+	p.link[dir] = n
+	q = n
+
+	// Update subtree sizes along the search path
+	for p, depth = head.link[directionRight], 0; p != q; p, depth = p.link[dirs[depth]], depth+1 {
+		p.size++
+	}
 
 	// Update balance factors
-	for p = s; p != q; p = p.link[dir] {
-		dir = directionOfBool(tree.compareKeys(p.key, key) < 0)
-		p.balance += dir.balance()
+	for p, depth = s, sDepth; p != q; p, depth = p.link[dirs[depth]], depth+1 {
+		p.balance += dirs[depth].balance()
 	}
 
 	q = s // Save rebalance point for parent fix
 
 	// Rebalance if necessary
 	if math.AbsSigned(s.balance) > 1 {
-		dir = directionOfBool(tree.compareKeys(s.key, key) < 0)
-		s = s.insertBalance(dir)
+		s = s.insertBalance(dirs[sDepth])
+		tree.rotations++
 	}
 
 	// Fix parent
@@ -114,38 +374,244 @@ func (tree *Tree[K, V]) Add(key K, value V) {
 		iter.update = true
 	}
 
+	if appending {
+		tree.rightmost = n
+	}
 	tree.length++
+	return n, old, true
 }
 
-// Remove any association with key from tree.
-func (tree *Tree[K, V]) Remove(key K) {
-	if tree.root == nil {
+// MergeSorted merges a batch of associations sorted in ascending key order into
+// the tree. The resolve function is called with the key, existing value and
+// incoming value for each association of the batch with a key that is already
+// present, including keys repeated in the batch, and its result is stored. The
+// incoming value is stored if resolve is nil. The tree and batch are walked in
+// lockstep and the tree is rebuilt if keys are added, for a total of O(n+m)
+// time. Trees with a maximum size (see WithMaxSize) are updated one
+// association at a time instead. Iterators are updated as if Add had been
+// called. Panics if the batch is not sorted.
+func (tree *Tree[K, V]) MergeSorted(pairs []Assoc[K, V], resolve func(k K, existing, incoming V) V) {
+	tree.checkMutable()
+	for i := 1; i < len(pairs); i++ {
+		if tree.compareKeys(pairs[i-1].Key, pairs[i].Key) > 0 {
+			panic("avltree: batch is not sorted")
+		}
+	}
+	if resolve == nil {
+		resolve = func(_ K, _, incoming V) V { return incoming }
+	}
+
+	if tree.maxSize > 0 {
+		for _, pair := range pairs {
+			if node, old, inserted := tree.add(pair.Key, pair.Value, false); !inserted {
+				node.value = resolve(node.key, old, pair.Value)
+				tree.stamp(node)
+			}
+		}
 		return
 	}
 
-	curr := tree.root
-	var up [maxTreeHeight]*node[K, V]
-	var upd [maxTreeHeight]direction
-	var top int
+	existing := tree.appendNodes(make([]*node[K, V], 0, tree.length))
+	merged := make([]*node[K, V], 0, tree.length+len(pairs))
 
-	// Search down tree and save path
-	for {
-		if curr == nil {
-			return
+	var i int
+	var inserted bool
+	for _, pair := range pairs {
+		for i < len(existing) && tree.compareKeys(existing[i].key, pair.Key) < 0 {
+			merged = append(merged, existing[i])
+			i++
+		}
+		if i < len(existing) && tree.compareKeys(existing[i].key, pair.Key) == 0 {
+			existing[i].value = resolve(existing[i].key, existing[i].value, pair.Value)
+			tree.stamp(existing[i])
+			continue
+		}
+		if last := len(merged) - 1; last >= 0 && tree.compareKeys(merged[last].key, pair.Key) == 0 {
+			// Key repeated in batch
+			merged[last].value = resolve(merged[last].key, merged[last].value, pair.Value)
+			tree.stamp(merged[last])
+			continue
 		}
 
-		cmp := tree.compareKeys(curr.key, key)
-		if cmp == 0 {
-			break
+		key := pair.Key
+		if tree.internKey != nil {
+			key = tree.internKey(key)
 		}
+		n := tree.newNode()
+		n.key, n.value = key, pair.Value
+		tree.stamp(n)
+		merged = append(merged, n)
+		inserted = true
+	}
+	if !inserted {
+		return
+	}
+	merged = append(merged, existing[i:]...)
 
-		// Push direction and node onto stack
-		upd[top] = directionOfBool(cmp < 0)
-		up[top] = curr
-		top++
+	tree.root, _ = linkBalanced(merged)
+	tree.length = len(merged)
+	tree.rightmost = nil
 
-		curr = curr.link[upd[top-1]]
+	// Mark all iterators for path update
+	for e := tree.iters.Next(); e != &tree.iters; e = e.Next() {
+		iter := e.Value
+		iter.update = true
 	}
+}
+
+// LoadSorted replaces all associations of the tree with associations sorted in
+// ascending key order. The onDuplicate function is called with the existing
+// and incoming value for keys repeated in the input and its result is stored.
+// The incoming value is stored if onDuplicate is nil. The tree is rebuilt in
+// O(n) time, except for trees with a maximum size (see WithMaxSize) that are
+// loaded one association at a time. All iterators are invalidated as if Clear
+// had been called. ErrUnsorted is returned and the tree is left unmodified if
+// the input is not sorted.
+func (tree *Tree[K, V]) LoadSorted(pairs []Assoc[K, V], onDuplicate func(existing, incoming V) V) error {
+	tree.checkMutable()
+	for i := 1; i < len(pairs); i++ {
+		if tree.compareKeys(pairs[i-1].Key, pairs[i].Key) > 0 {
+			return ErrUnsorted
+		}
+	}
+	if onDuplicate == nil {
+		onDuplicate = func(_, incoming V) V { return incoming }
+	}
+
+	tree.Clear(nil)
+
+	if tree.maxSize > 0 {
+		for _, pair := range pairs {
+			if node, old, inserted := tree.add(pair.Key, pair.Value, false); !inserted {
+				node.value = onDuplicate(old, pair.Value)
+				tree.stamp(node)
+			}
+		}
+		return nil
+	}
+
+	nodes := make([]*node[K, V], 0, len(pairs))
+	for _, pair := range pairs {
+		if last := len(nodes) - 1; last >= 0 && tree.compareKeys(nodes[last].key, pair.Key) == 0 {
+			nodes[last].value = onDuplicate(nodes[last].value, pair.Value)
+			tree.stamp(nodes[last])
+			continue
+		}
+
+		key := pair.Key
+		if tree.internKey != nil {
+			key = tree.internKey(key)
+		}
+		n := tree.newNode()
+		n.key, n.value = key, pair.Value
+		tree.stamp(n)
+		nodes = append(nodes, n)
+	}
+
+	tree.root, _ = linkBalanced(nodes)
+	tree.length = len(nodes)
+	return nil
+}
+
+// UpdateKey replaces the stored key of the association for key with newKey and
+// reports true. This allows updating parts of a key that does not affect its
+// ordering. The tree is not modified and false is returned if no association
+// was found or if newKey does not compare equal to key.
+func (tree *Tree[K, V]) UpdateKey(key K, newKey K) bool {
+	tree.checkMutable()
+	if tree.compareKeys(key, newKey) != 0 {
+		return false
+	}
+	if node := tree.findNode(key); node != nil {
+		node.key = newKey
+		tree.stamp(node)
+		return true
+	}
+	return false
+}
+
+// Remove any association with key from tree.
+func (tree *Tree[K, V]) Remove(key K) {
+	tree.remove(func(n *node[K, V]) int {
+		return tree.compareKeys(n.key, key)
+	})
+}
+
+// RemoveAndGet removes any association with key from tree and returns its value
+// and true. The zero value of V and false is returned if no association was
+// found. The tree is descended once.
+func (tree *Tree[K, V]) RemoveAndGet(key K) (V, bool) {
+	_, value, ok := tree.remove(func(n *node[K, V]) int {
+		return tree.compareKeys(n.key, key)
+	})
+	return value, ok
+}
+
+// RemoveRange removes all associations with keys in the inclusive range lo to
+// hi and returns the number of removed associations. A non-nil release function
+// is called on each removed association. Iterators are updated as if Remove had
+// been called for each key.
+func (tree *Tree[K, V]) RemoveRange(lo, hi K, release func(K, V)) int {
+	tree.checkMutable()
+	keys := make([]K, 0, tree.CountRange(lo, hi))
+	tree.applyFrom(lo, cap(keys), func(k K, _ V) {
+		keys = append(keys, k)
+	})
+	for _, key := range keys {
+		k, v, _ := tree.remove(func(n *node[K, V]) int {
+			return tree.compareKeys(n.key, key)
+		})
+		if release != nil {
+			release(k, v)
+		}
+	}
+	return len(keys)
+}
+
+// PopLowest removes the association with the lowest key from the tree and
+// returns it and true. The zero values of K and V and false is returned if the
+// tree is empty. The tree is descended once and iterators are updated as if
+// Remove had been called.
+func (tree *Tree[K, V]) PopLowest() (K, V, bool) {
+	return tree.remove(func(n *node[K, V]) int {
+		if n.link[directionLeft] == nil {
+			return 0
+		}
+		return 1
+	})
+}
+
+// PopHighest removes the association with the highest key from the tree and
+// returns it and true. The zero values of K and V and false is returned if the
+// tree is empty. The tree is descended once and iterators are updated as if
+// Remove had been called.
+func (tree *Tree[K, V]) PopHighest() (K, V, bool) {
+	return tree.remove(func(n *node[K, V]) int {
+		if n.link[directionRight] == nil {
+			return 0
+		}
+		return -1
+	})
+}
+
+// Remove the association of the node found by descending the tree according to
+// search, which compares the key of a node with the key searched for. Returns
+// the removed association and true or the zero values of K and V and false if
+// no association was found.
+func (tree *Tree[K, V]) remove(search func(*node[K, V]) int) (K, V, bool) {
+	tree.checkMutable()
+	var path nodePath[K, V]
+	curr := path.descend(tree.root, search)
+	if curr == nil {
+		return zeroAssoc[K, V]()
+	}
+	return tree.removeAt(curr, &path)
+}
+
+// Remove node curr found by descending the tree along path and return its
+// association and true.
+func (tree *Tree[K, V]) removeAt(curr *node[K, V], path *nodePath[K, V]) (K, V, bool) {
+	up, upd, top := &path.up, &path.upd, path.top
 
 	// Remove the node
 	if curr.link[directionLeft] == nil || curr.link[directionRight] == nil {
@@ -175,15 +641,20 @@ func (tree *Tree[K, V]) Remove(key K) {
 		}
 
 		// Swap associations
-		tmpKey, tmpValue := curr.key, curr.value
-		curr.key, curr.value = heir.key, heir.value
-		heir.key, heir.value = tmpKey, tmpValue
+		tmpKey, tmpValue, tmpSeq := curr.key, curr.value, curr.seq
+		curr.key, curr.value, curr.seq = heir.key, heir.value, heir.seq
+		heir.key, heir.value, heir.seq = tmpKey, tmpValue, tmpSeq
 
 		// Unlink successor and fix parent
 		up[top-1].link[directionOfBool(up[top-1] == curr)] = heir.link[directionRight]
 		curr = heir
 	}
 
+	// Update subtree sizes along the search path
+	for i := 0; i < top; i++ {
+		up[i].size--
+	}
+
 	// Walk back up the search path
 	var done bool
 
@@ -196,6 +667,7 @@ func (tree *Tree[K, V]) Remove(key K) {
 			break
 		} else if math.AbsSigned(up[top].balance) > 1 {
 			up[top], done = up[top].removeBalance(upd[top])
+			tree.rotations++
 
 			// Fix parent
 			if top != 0 {
@@ -224,8 +696,96 @@ func (tree *Tree[K, V]) Remove(key K) {
 		}
 	}
 
-	tree.nodePool.put(curr, nil)
+	if curr == tree.rightmost {
+		tree.rightmost = nil
+	}
+	key, value := curr.key, curr.value
+	tree.freeNode(curr, nil)
 	tree.length--
+	return key, value, true
+}
+
+// Insert an association at the empty link that path leads to and rebalance the
+// tree bottom up along path.
+func (tree *Tree[K, V]) insertAt(key K, value V, path *nodePath[K, V]) {
+	if tree.internKey != nil {
+		key = tree.internKey(key)
+	}
+	n := tree.newNode()
+	n.key, n.value = key, value
+	n.size = 1
+	tree.stamp(n)
+
+	if path.top == 0 {
+		tree.root = n
+	} else {
+		parent, dir := path.up[path.top-1], path.upd[path.top-1]
+		parent.link[dir] = n
+		if parent == tree.rightmost && dir == directionRight {
+			tree.rightmost = n
+		}
+	}
+
+	// Update subtree sizes along the search path
+	for i := 0; i < path.top; i++ {
+		path.up[i].size++
+	}
+
+	// Walk back up the search path updating balance factors until the height
+	// of a subtree is unchanged
+	for i := path.top - 1; i >= 0; i-- {
+		p := path.up[i]
+		p.balance += path.upd[i].balance()
+		if p.balance == 0 {
+			break
+		} else if math.AbsSigned(p.balance) > 1 {
+			p = p.insertBalance(path.upd[i])
+			tree.rotations++
+
+			// Fix parent
+			if i != 0 {
+				path.up[i-1].link[path.upd[i-1]] = p
+			} else {
+				tree.root = p
+			}
+			break
+		}
+	}
+
+	// Mark all iterators for path update
+	for e := tree.iters.Next(); e != &tree.iters; e = e.Next() {
+		e.Value.update = true
+	}
+	tree.length++
+}
+
+// Path from the root of a tree recorded while descending it.
+type nodePath[K, V any] struct {
+	up  [maxTreeHeight]*node[K, V] // Nodes above the current node
+	upd [maxTreeHeight]direction   // Directions taken from the nodes
+	top int                        // Number of nodes on the path
+}
+
+// Descend the tree rooted at root according to search, which compares the key
+// of a node with the key searched for, and record the path taken. Returns the
+// node found or nil, in which case the path leads to the empty link where the
+// key belongs.
+func (path *nodePath[K, V]) descend(root *node[K, V], search func(*node[K, V]) int) *node[K, V] {
+	curr := root
+	for curr != nil {
+		cmp := search(curr)
+		if cmp == 0 {
+			break
+		}
+
+		// Push direction and node onto stack
+		path.upd[path.top] = directionOfBool(cmp < 0)
+		path.up[path.top] = curr
+		path.top++
+
+		curr = curr.link[path.upd[path.top-1]]
+	}
+	return curr
 }
 
 // Clear removes all associations from the tree and invalidates all iterators. A
@@ -233,6 +793,7 @@ func (tree *Tree[K, V]) Remove(key K) {
 // release function must not fail. Remove each association by itself (for
 // example by using an iterator) if it can fail and handle errors properly.
 func (tree *Tree[K, V]) Clear(release func(K, V)) {
+	tree.checkMutable()
 	curr := tree.root
 
 	// Destruction by rotation
@@ -242,7 +803,7 @@ func (tree *Tree[K, V]) Clear(release func(K, V)) {
 		if curr.link[directionLeft] == nil {
 			// Remove node
 			save = curr.link[directionRight]
-			tree.nodePool.put(curr, release)
+			tree.freeNode(curr, release)
 		} else {
 			// Rotate right
 			save = curr.link[directionLeft]
@@ -254,12 +815,130 @@ func (tree *Tree[K, V]) Clear(release func(K, V)) {
 
 	tree.root = nil
 	tree.length = 0
+	tree.rightmost = nil
+	tree.arena.reset()
 
 	for tree.iters.IsLinked() {
 		tree.iters.Next().Value.Close()
 	}
 }
 
+// Split moves the associations with keys less than key to a new left tree and
+// the remaining associations to a new right tree, leaving the tree empty. Both
+// trees use the same compare function and options as the tree. The trees are
+// rebuilt in O(n) time and all iterators of the tree are invalidated as if
+// Clear had been called.
+func (tree *Tree[K, V]) Split(key K) (left, right *Tree[K, V]) {
+	tree.checkMutable()
+	nodes := tree.appendNodes(make([]*node[K, V], 0, tree.length))
+	i := sort.Search(len(nodes), func(i int) bool {
+		return tree.compareKeys(nodes[i].key, key) >= 0
+	})
+
+	// Nodes of an arena can't be handed over to another tree.
+	owned := tree.arena == nil
+
+	left, right = tree.newEmpty(), tree.newEmpty()
+	left.adoptNodes(nodes[:i], owned)
+	right.adoptNodes(nodes[i:], owned)
+	left.seq, right.seq = tree.seq, tree.seq
+
+	if owned {
+		tree.root, tree.length, tree.rightmost = nil, 0, nil
+		for tree.iters.IsLinked() {
+			tree.iters.Next().Value.Close()
+		}
+	} else {
+		tree.Clear(nil)
+	}
+	return
+}
+
+// SplitTopN removes the n associations with the highest keys from the tree and
+// returns them in a new tree using the same compare function and options. All
+// associations are moved if n is larger than the length of the tree and none
+// if n is less than one. Iterators are updated as if Remove had been called.
+func (tree *Tree[K, V]) SplitTopN(n int) *Tree[K, V] {
+	n = math.MaxInteger(0, math.MinInteger(n, tree.length))
+
+	keys, values := make([]K, n), make([]V, n)
+	for i := n - 1; i >= 0; i-- {
+		keys[i], values[i], _ = tree.PopHighest()
+	}
+
+	top := tree.newEmpty()
+	top.root, _ = top.buildBalanced(keys, values)
+	top.length = n
+	return top
+}
+
+// Clone returns a copy of the tree using the same compare function and options.
+// Keys and values are copied by assignment while all nodes are newly allocated,
+// so modifying one of the trees does not affect the other. The copy is not
+// frozen (see Freeze) and keeps the sequence numbers of the tree (see
+// WithSequence). It runs in O(n) time.
+func (tree *Tree[K, V]) Clone() *Tree[K, V] {
+	clone := tree.newEmpty()
+	clone.adoptNodes(tree.appendNodes(make([]*node[K, V], 0, tree.length)), false)
+	clone.seq = tree.seq
+	return clone
+}
+
+// RemapKeys returns a new tree holding the associations of the tree with each
+// key replaced by the result of calling remap on it. The new tree is ordered by
+// newCompare and otherwise use the same options as the tree. The tree itself is
+// not modified. Associations whose remapped keys compare equal are overwritten
+// in ascending order of their original keys, as if added by Add.
+func (tree *Tree[K, V]) RemapKeys(newCompare math.Comparator[K], remap func(K) K) *Tree[K, V] {
+	remapped := tree.newEmpty()
+	remapped.compareKeys = newCompare
+	tree.Apply(func(k K, v V) {
+		remapped.Add(remap(k), v)
+	})
+	return remapped
+}
+
+// Freeze disallows modifications of the tree. Operations that modify the tree,
+// such as Add, Remove and Clear, panics until Unfreeze is called. Read
+// operations are not affected.
+func (tree *Tree[K, V]) Freeze() {
+	tree.frozen = true
+}
+
+// Unfreeze allows modifications of a tree disallowed by Freeze.
+func (tree *Tree[K, V]) Unfreeze() {
+	tree.frozen = false
+}
+
+// Panic if modifications of the tree are disallowed.
+func (tree *Tree[K, V]) checkMutable() {
+	if tree.frozen {
+		panic("avltree: tree is frozen")
+	}
+}
+
+// RotationCount returns the number of rotations performed to rebalance the tree
+// since it was created. Single and double rotations are counted as one
+// rotation each.
+func (tree *Tree[K, V]) RotationCount() uint64 {
+	return tree.rotations
+}
+
+// Sequence returns the sequence number of the last modification of an
+// association in a tree using the WithSequence option. Zero is returned if no
+// association has been modified.
+func (tree *Tree[K, V]) Sequence() uint64 {
+	return tree.seq
+}
+
+// Stamp node with the next sequence number if the tree is sequenced.
+func (tree *Tree[K, V]) stamp(node *node[K, V]) {
+	if tree.sequenced {
+		tree.seq++
+		node.seq = tree.seq
+	}
+}
+
 // Length returns the number of associations in the tree.
 func (tree *Tree[K, V]) Length() int {
 	return tree.length
@@ -268,101 +947,798 @@ func (tree *Tree[K, V]) Length() int {
 // Find value associated with key. Returns the found value and true or the zero
 // value of V and false if no assocation was found.
 func (tree *Tree[K, V]) Find(key K) (V, bool) {
+	if node := tree.findNode(key); node != nil {
+		return node.value, true
+	}
+	return zeroValue[V]()
+}
+
+// FindOr returns the value associated with key or fallback if no association
+// was found.
+func (tree *Tree[K, V]) FindOr(key K, fallback V) V {
+	if node := tree.findNode(key); node != nil {
+		return node.value
+	}
+	return fallback
+}
+
+// Contains reports whether the tree holds an association for key.
+func (tree *Tree[K, V]) Contains(key K) bool {
+	return tree.findNode(key) != nil
+}
+
+// ContainsAll reports whether the tree holds associations for all keys. True is
+// returned for an empty slice of keys.
+func (tree *Tree[K, V]) ContainsAll(keys []K) bool {
+	for _, key := range keys {
+		if !tree.Contains(key) {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsAny reports whether the tree holds an association for any of the
+// keys. False is returned for an empty slice of keys.
+func (tree *Tree[K, V]) ContainsAny(keys []K) bool {
+	for _, key := range keys {
+		if tree.Contains(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindEqualOrLesser returns the association that match key or the association
+// with the immediately lesser key and true. The zero values of K and V and
+// false is returned if no assocation was found.
+func (tree *Tree[K, V]) FindEqualOrLesser(key K) (K, V, bool) {
+	return tree.FloorInclusive(key)
+}
+
+// FindEqualOrGreater returns the association that match key or the immediately
+// greater association and true. The zero values of K and V and false is
+// returned if no assocation was found.
+func (tree *Tree[K, V]) FindEqualOrGreater(key K) (K, V, bool) {
+	return tree.CeilInclusive(key)
+}
+
+// FloorInclusive returns the association with the greatest key that is less
+// than or equal to key and true. The zero values of K and V and false is
+// returned if all keys are greater than key.
+func (tree *Tree[K, V]) FloorInclusive(key K) (K, V, bool) {
+	return tree.findNear(key, directionLeft, true).assoc()
+}
+
+// FloorExclusive returns the association with the greatest key that is
+// strictly less than key and true. The zero values of K and V and false is
+// returned if all keys are greater than or equal to key.
+func (tree *Tree[K, V]) FloorExclusive(key K) (K, V, bool) {
+	return tree.findNear(key, directionLeft, false).assoc()
+}
+
+// CeilInclusive returns the association with the least key that is greater
+// than or equal to key and true. The zero values of K and V and false is
+// returned if all keys are less than key.
+func (tree *Tree[K, V]) CeilInclusive(key K) (K, V, bool) {
+	return tree.findNear(key, directionRight, true).assoc()
+}
+
+// CeilExclusive returns the association with the least key that is strictly
+// greater than key and true. The zero values of K and V and false is returned
+// if all keys are less than or equal to key.
+func (tree *Tree[K, V]) CeilExclusive(key K) (K, V, bool) {
+	return tree.findNear(key, directionRight, false).assoc()
+}
+
+// FindWithNeighbors returns the value associated with key and true together
+// with the greatest key strictly less than key and the least key strictly
+// greater than key, each with true if it exists. The neighbors are reported
+// whether or not key itself is in the tree. The tree is descended once.
+func (tree *Tree[K, V]) FindWithNeighbors(key K) (value V, found bool, prevKey K, prevOK bool, nextKey K, nextOK bool) {
+	var near [2]*node[K, V]
+
 	curr := tree.root
 	for curr != nil {
 		cmp := tree.compareKeys(curr.key, key)
 		if cmp == 0 {
+			value, found = curr.value, true
 			break
 		}
-		curr = curr.link[directionOfBool(cmp < 0)]
+		dir := directionOfBool(cmp < 0)
+		near[dir.other()] = curr
+		curr = curr.link[dir]
 	}
+
+	// The neighbors of a matching node with children are in its subtrees.
 	if curr != nil {
-		return curr.value, true
+		for _, dir := range [2]direction{directionLeft, directionRight} {
+			if n := curr.link[dir]; n != nil {
+				for n.link[dir.other()] != nil {
+					n = n.link[dir.other()]
+				}
+				near[dir] = n
+			}
+		}
 	}
-	return zeroValue[V]()
+
+	prevKey, _, prevOK = near[directionLeft].assoc()
+	nextKey, _, nextOK = near[directionRight].assoc()
+	return
 }
 
-// FindEqualOrLesser returns the association that match key or the association
-// with the immediately lesser key and true. The zero values of K and V and
-// false is returned if no assocation was found.
-func (tree *Tree[K, V]) FindEqualOrLesser(key K) (K, V, bool) {
-	var lesser *node[K, V]
+// ApproxPercentile returns an estimate of the key at percentile p and true. The
+// percentile is clamped to the range 0 (lowest key) to 1 (highest key). Only
+// every sampleEvery key, counting from the lowest key, is considered a
+// candidate, so the rank of the returned key is within sampleEvery ranks of the
+// exact percentile rank; a sampleEvery of one yields the exact result. The tree
+// is walked in ascending key order up to the selected key, which takes O(rank)
+// time whatever sampleEvery is; a larger sampleEvery is not faster, it only
+// rounds the result to a coarser rank. The zero value of K and false is
+// returned if the tree is empty.
+func (tree *Tree[K, V]) ApproxPercentile(p float64, sampleEvery int) (K, bool) {
+	if tree.length == 0 {
+		var zero K
+		return zero, false
+	}
+
+	if !(p >= 0) { // Also true for NaN
+		p = 0
+	} else if p > 1 {
+		p = 1
+	}
+	sampleEvery = math.MaxInteger(1, sampleEvery)
 
+	// Pick the sample nearest to the exact position.
+	last := tree.length - 1
+	rank := int(p*float64(last)/float64(sampleEvery)+0.5) * sampleEvery
+	if rank > last {
+		rank -= sampleEvery
+	}
+
+	iter := tree.NewIterator()
+	defer iter.Close()
+	k, _, _ := iter.Next()
+	for i := 0; i < rank; i++ {
+		k, _, _ = iter.Next()
+	}
+	return k, true
+}
+
+// ClampKey returns the association for key clamped to the key range of the
+// tree and true. The association with the lowest key is returned if key is
+// less than the lowest key and the association with the highest key is
+// returned if key is greater than the highest key. Within range, the
+// association that match key or the immediately greater association is
+// returned. The zero values of K and V and false is returned if the tree is
+// empty.
+func (tree *Tree[K, V]) ClampKey(key K) (K, V, bool) {
+	if node := tree.findNear(key, directionRight, true); node != nil {
+		return node.assoc()
+	}
+	return tree.FindHighest()
+}
+
+// AtFraction returns the association at the fractional position f of the
+// associations in ascending key order and true. The position is clamped to the
+// range 0 (lowest key) to 1 (highest key) and rounded to the nearest rank. The
+// zero values of K and V and false is returned if the tree is empty.
+func (tree *Tree[K, V]) AtFraction(f float64) (K, V, bool) {
+	if !(f >= 0) { // Also true for NaN
+		f = 0
+	} else if f > 1 {
+		f = 1
+	}
+	return tree.selectNode(int(f*float64(tree.length-1) + 0.5)).assoc()
+}
+
+// Select returns the association with the given zero based rank in ascending
+// key order and true. The zero values of K and V and false is returned if rank
+// is negative or not less than Length. It runs in O(log n) time.
+func (tree *Tree[K, V]) Select(rank int) (K, V, bool) {
+	return tree.selectNode(rank).assoc()
+}
+
+// Rank returns the number of associations with keys strictly less than key and
+// true if an association for key exists. The rank of a key that is not in the
+// tree is the rank it would get if added. It runs in O(log n) time.
+func (tree *Tree[K, V]) Rank(key K) (int, bool) {
+	rank := 0
 	curr := tree.root
 	for curr != nil {
 		cmp := tree.compareKeys(curr.key, key)
 		if cmp == 0 {
-			break
+			return rank + curr.link[directionLeft].subtreeSize(), true
 		}
 		if cmp < 0 {
-			lesser = curr
+			rank += curr.link[directionLeft].subtreeSize() + 1
+		}
+		curr = curr.link[directionOfBool(cmp < 0)]
+	}
+	return rank, false
+}
+
+// CountRange returns the number of associations with keys in the inclusive
+// range lo to hi. Zero is returned if lo is greater than hi. It runs in
+// O(log n) time.
+func (tree *Tree[K, V]) CountRange(lo, hi K) int {
+	below, _ := tree.Rank(lo)
+	upTo, found := tree.Rank(hi)
+	if found {
+		upTo++
+	}
+	return math.MaxInteger(0, upTo-below)
+}
+
+// Median returns the median association and true. The lower median is returned
+// for trees with an even number of associations. The zero values of K and V and
+// false is returned if the tree is empty.
+func (tree *Tree[K, V]) Median() (K, V, bool) {
+	return tree.selectNode((tree.length - 1) / 2).assoc()
+}
+
+// ArgMin returns the association with the lowest score and true. Scores are
+// computed by calling score on each association in ascending key order and
+// ties are resolved in favor of the lowest key. The zero values of K and V and
+// false is returned if the tree is empty.
+func (tree *Tree[K, V]) ArgMin(score func(K, V) int) (K, V, bool) {
+	return tree.argBest(score, func(lhs, rhs int) bool { return lhs < rhs })
+}
+
+// ArgMax returns the association with the highest score and true. Scores are
+// computed by calling score on each association in ascending key order and
+// ties are resolved in favor of the lowest key. The zero values of K and V and
+// false is returned if the tree is empty.
+func (tree *Tree[K, V]) ArgMax(score func(K, V) int) (K, V, bool) {
+	return tree.argBest(score, func(lhs, rhs int) bool { return lhs > rhs })
+}
+
+// Return the first association in ascending key order with the best score, as
+// ordered by better.
+func (tree *Tree[K, V]) argBest(score func(K, V) int, better func(lhs, rhs int) bool) (K, V, bool) {
+	var best *node[K, V]
+	var bestScore int
+	tree.walkNodes(func(n *node[K, V]) bool {
+		if s := score(n.key, n.value); best == nil || better(s, bestScore) {
+			best, bestScore = n, s
+		}
+		return true
+	})
+	return best.assoc()
+}
+
+// Height returns the number of nodes on the longest path from the root to a
+// leaf. Zero is returned for an empty tree. The balance factors of the nodes
+// are followed down the taller subtree in O(log n) time.
+func (tree *Tree[K, V]) Height() int {
+	height := 0
+	for node := tree.root; node != nil; height++ {
+		node = node.link[directionOfBool(node.balance >= 0)]
+	}
+	return height
+}
+
+// Depth returns the number of edges from the root to the node holding key and
+// true. The root node is at depth zero. Zero and false is returned if no
+// association was found.
+func (tree *Tree[K, V]) Depth(key K) (int, bool) {
+	depth := 0
+	curr := tree.root
+	for curr != nil {
+		cmp := tree.compareKeys(curr.key, key)
+		if cmp == 0 {
+			return depth, true
+		}
+		curr = curr.link[directionOfBool(cmp < 0)]
+		depth++
+	}
+	return 0, false
+}
+
+// FindLowest returns the association with the lowest key and true. The zero value
+// of K and V and false is returned if the tree is empty.
+func (tree *Tree[K, V]) FindLowest() (K, V, bool) {
+	return tree.edgeNode(directionLeft)
+}
+
+// FindHighest returns the association with the highest key and true. The zero value
+// of K and V and false is returned if the tree is empty.
+func (tree *Tree[K, V]) FindHighest() (K, V, bool) {
+	return tree.edgeNode(directionRight)
+}
+
+// Apply calls the supplied function for each association in the tree.
+func (tree *Tree[K, V]) Apply(f func(K, V)) {
+	iter := tree.NewIterator()
+	for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
+		f(k, v)
+	}
+}
+
+// ApplyRange calls the supplied function for each association with a key in the
+// inclusive range lo to hi in ascending key order. Only the associations in
+// the range are visited.
+func (tree *Tree[K, V]) ApplyRange(lo, hi K, f func(K, V)) {
+	iter := tree.NewRangeIterator(lo, hi)
+	for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
+		f(k, v)
+	}
+}
+
+// ApplyContext calls the supplied function for each association in the tree
+// until the function returns an error or the context is done. The error
+// returned by the function or the error of the context is returned. The context
+// is checked before the first association and then periodically.
+func (tree *Tree[K, V]) ApplyContext(ctx context.Context, f func(K, V) error) error {
+	iter := tree.NewIterator()
+	defer iter.Close()
+
+	i := 0
+	for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
+		if i%applyContextInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if err := f(k, v); err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
+
+// CollectSorted returns a slice holding the result of calling project on each
+// association in the tree in ascending key order. The slice is nil for an empty
+// tree.
+func CollectSorted[K, V, T any](tree *Tree[K, V], project func(K, V) T) []T {
+	if tree.length == 0 {
+		return nil
+	}
+	result := make([]T, 0, tree.length)
+	tree.Apply(func(k K, v V) {
+		result = append(result, project(k, v))
+	})
+	return result
+}
+
+// ParallelApply calls the supplied function for each association in the tree,
+// dividing the associations into the given number of contiguous key ranges
+// that are processed by separate go routines. Associations within a range are
+// visited in ascending key order. The function must be safe for concurrent use
+// and the tree must not be modified until ParallelApply returns. At least one
+// and at most Length ranges are used.
+func (tree *Tree[K, V]) ParallelApply(parts int, f func(K, V)) {
+	parts = math.MaxInteger(1, math.MinInteger(parts, tree.length))
+	if tree.length == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(parts)
+	for i := 0; i < parts; i++ {
+		start := tree.selectNode(i * tree.length / parts).key
+		n := (i+1)*tree.length/parts - i*tree.length/parts
+		go func(start K, n int) {
+			defer wg.Done()
+			tree.applyFrom(start, n, f)
+		}(start, n)
+	}
+	wg.Wait()
+}
+
+// ApplyLeaves calls the supplied function for each association held by a leaf
+// node, a node without children, in ascending key order.
+func (tree *Tree[K, V]) ApplyLeaves(f func(K, V)) {
+	tree.walkNodes(func(node *node[K, V]) bool {
+		if node.link[directionLeft] == nil && node.link[directionRight] == nil {
+			f(node.key, node.value)
+		}
+		return true
+	})
+}
+
+// ApplyLevelOrder calls the supplied function for each association in the tree
+// in breadth first order, visiting nodes level by level from the root and each
+// level from low to high key values. The level of the root is zero. Unlike
+// Apply, the visiting order exposes the shape of the tree.
+func (tree *Tree[K, V]) ApplyLevelOrder(f func(level int, k K, v V)) {
+	type levelNode struct {
+		node  *node[K, V]
+		level int
+	}
+
+	queue := list.New[levelNode]()
+	enqueue := func(node *node[K, V], level int) {
+		if node != nil {
+			e := list.New[levelNode]()
+			e.Value = levelNode{node, level}
+			queue.LinkPrev(e)
+		}
+	}
+
+	enqueue(tree.root, 0)
+	for queue.IsLinked() {
+		e := queue.Next()
+		e.Unlink()
+		node, level := e.Value.node, e.Value.level
+		f(level, node.key, node.value)
+		enqueue(node.link[directionLeft], level+1)
+		enqueue(node.link[directionRight], level+1)
+	}
+}
+
+// NewIterator creates an iterator that advances from low to high key values.
+// Make sure to close the iterator by calling its Close method when done.
+func (tree *Tree[K, V]) NewIterator() *Iterator[K, V] {
+	return tree.iterator(directionRight)
+}
+
+// NewReverseIterator creates an iterator that advances from high to low key
+// values. Make sure to close the iterator by calling its Close method when
+// done.
+func (tree *Tree[K, V]) NewReverseIterator() *Iterator[K, V] {
+	return tree.iterator(directionLeft)
+}
+
+// NewSnapshotIterator creates an iterator that advances from low to high key
+// values over the associations of the tree at the time the iterator is created.
+// Later modifications of the tree do not affect the iterator. The associations
+// are copied to a private tree, which costs O(n) time and memory, that is
+// released when the iterator is closed.
+func (tree *Tree[K, V]) NewSnapshotIterator() *Iterator[K, V] {
+	return tree.snapshot().iterator(directionRight)
+}
+
+// NewSnapshotReverseIterator creates an iterator like NewSnapshotIterator that
+// advances from high to low key values.
+func (tree *Tree[K, V]) NewSnapshotReverseIterator() *Iterator[K, V] {
+	return tree.snapshot().iterator(directionLeft)
+}
+
+// Return a copy of the tree allocating its nodes from an arena.
+func (tree *Tree[K, V]) snapshot() *Tree[K, V] {
+	snap := tree.newEmpty()
+	snap.allocator, snap.arena = nil, &nodeArena[K, V]{}
+	snap.adoptNodes(tree.appendNodes(make([]*node[K, V], 0, tree.length)), false)
+	return snap
+}
+
+// NewUntrackedIterator creates an iterator that advances from low to high key
+// values without registering with the tree for updates. It's cheaper to create
+// than an iterator returned by NewIterator but the tree must not be modified
+// while it's in use; the behavior is undefined if it is. Calling Close is
+// optional but harmless.
+func (tree *Tree[K, V]) NewUntrackedIterator() *Iterator[K, V] {
+	iter := tree.unlinkedIterator(directionRight)
+	iter.buildPathStart()
+	return iter
+}
+
+// NewRangeIterator creates an iterator that advances from low to high key values
+// over associations with keys in the inclusive range lo to hi. No associations
+// are visited if lo is greater than hi. The iterator is updated by tree
+// modifications like an iterator created by NewIterator but never advances
+// past hi. Make sure to close the iterator by calling its Close method when
+// done.
+func (tree *Tree[K, V]) NewRangeIterator(lo, hi K) *Iterator[K, V] {
+	return tree.rangeIterator(directionRight, lo, hi)
+}
+
+// NewRangeReverseIterator creates an iterator that advances from high to low key
+// values over associations with keys in the inclusive range lo to hi. It's the
+// reverse of NewRangeIterator. Make sure to close the iterator by calling its
+// Close method when done.
+func (tree *Tree[K, V]) NewRangeReverseIterator(lo, hi K) *Iterator[K, V] {
+	return tree.rangeIterator(directionLeft, hi, lo)
+}
+
+// Create an iterator advancing in direction dir from the key nearest to start
+// in that direction (inclusive) until bound has been passed.
+func (tree *Tree[K, V]) rangeIterator(dir direction, start, bound K) *Iterator[K, V] {
+	iter := tree.unlinkedIterator(dir)
+	if node := tree.findNear(start, dir, true); node != nil {
+		iter.bounded, iter.start, iter.bound = true, start, bound
+		iter.linkAt(node)
+	}
+	return iter
+}
+
+// NewRankRangeIterator creates an iterator that advances from low to high key
+// values over associations with a zero based rank from loRank (inclusive) to
+// hiRank (exclusive). Ranks are clamped to the range 0 to Length and no
+// associations are visited if loRank is not less than hiRank. The ranks are
+// translated to the keys at those ranks when the iterator is created; the
+// iterator then behaves like a key bounded iterator with respect to tree
+// modifications. Make sure to close the iterator by calling its Close method
+// when done.
+func (tree *Tree[K, V]) NewRankRangeIterator(loRank, hiRank int) *Iterator[K, V] {
+	loRank = math.MaxInteger(0, math.MinInteger(loRank, tree.length))
+	hiRank = math.MaxInteger(0, math.MinInteger(hiRank, tree.length))
+
+	iter := tree.unlinkedIterator(directionRight)
+	if loRank < hiRank {
+		start := tree.selectNode(loRank)
+		iter.bounded, iter.start, iter.bound = true, start.key, tree.selectNode(hiRank-1).key
+		iter.linkAt(start)
+	}
+	return iter
+}
+
+// NewGapIterator creates an iterator that reports gaps in the key sequence of
+// the tree. The next function returns the key expected to follow a given key.
+// Walking from low to high key values, a pair of the expected key and the
+// actual next key is produced whenever the two differ according to the
+// compare function of the tree. The iterator is exhausted after the highest
+// key has been visited. Make sure to close the iterator by calling its Close
+// method when done using it unless it's exhausted.
+func (tree *Tree[K, V]) NewGapIterator(next func(K) K) *GapIterator[K, V] {
+	return &GapIterator[K, V]{
+		iter:        tree.NewIterator(),
+		compareKeys: tree.compareKeys,
+		next:        next,
+	}
+}
+
+// FirstGapAtLeast returns the first pair of adjacent keys in ascending order
+// whose difference is at least d and true. The difference is computed by sub
+// and compared with d using the compare function of the tree. The zero values
+// of K and false is returned if there is no such pair.
+func (tree *Tree[K, V]) FirstGapAtLeast(d K, sub func(hi, lo K) K) (loKey, hiKey K, ok bool) {
+	var prev *node[K, V]
+	tree.walkNodes(func(n *node[K, V]) bool {
+		if prev != nil && tree.compareKeys(sub(n.key, prev.key), d) >= 0 {
+			loKey, hiKey, ok = prev.key, n.key, true
+			return false
+		}
+		prev = n
+		return true
+	})
+	return
+}
+
+// Remove an association to make room for key and value. See WithMaxSize.
+func (tree *Tree[K, V]) evictFor(key K, value V) {
+	if tree.evictOldest {
+		var oldest *node[K, V]
+		tree.walkNodes(func(n *node[K, V]) bool {
+			if oldest == nil || n.seq < oldest.seq {
+				oldest = n
+			}
+			return true
+		})
+		k, v := oldest.key, oldest.value
+		tree.Remove(k)
+		if tree.onEvict != nil {
+			tree.onEvict(k, v)
+		}
+		return
+	}
+	if tree.evict != nil {
+		if victim, ok := tree.evict(key, value); ok {
+			length := tree.length
+			if tree.Remove(victim); tree.length < length {
+				return
+			}
+		}
+	}
+	tree.PopLowest()
+}
+
+// Find node holding key or nil if no such node exist.
+func (tree *Tree[K, V]) findNode(key K) *node[K, V] {
+	curr := tree.root
+	for curr != nil {
+		cmp := tree.compareKeys(curr.key, key)
+		if cmp == 0 {
+			break
+		}
+		curr = curr.link[directionOfBool(cmp < 0)]
+	}
+	return curr
+}
+
+// Find node holding the key nearest to key on the lesser (directionLeft) or
+// greater (directionRight) side of it. The node holding key itself is included
+// if inclusive is true. Returns nil if no such node exist.
+func (tree *Tree[K, V]) findNear(key K, dir direction, inclusive bool) *node[K, V] {
+	var near *node[K, V]
+
+	curr := tree.root
+	for curr != nil {
+		cmp := tree.compareKeys(curr.key, key)
+		if cmp == 0 {
+			if inclusive {
+				return curr
+			}
+			curr = curr.link[dir]
+			continue
+		}
+		if directionOfBool(cmp > 0) == dir {
+			near = curr
 		}
 		curr = curr.link[directionOfBool(cmp < 0)]
 	}
-	if curr != nil {
-		return curr.key, curr.value, true
-	} else if lesser != nil {
-		return lesser.key, lesser.value, true
+	return near
+}
+
+// Call f for at most n associations in ascending key order starting with the
+// least key that is equal to or greater than key. The tree iterator list is not
+// used which makes it safe to call concurrently on an unmodified tree.
+func (tree *Tree[K, V]) applyFrom(key K, n int, f func(K, V)) {
+	var path [maxTreeHeight]*node[K, V]
+	var top int
+
+	// Save path of nodes with keys equal to or greater than key.
+	for curr := tree.root; curr != nil; {
+		cmp := tree.compareKeys(curr.key, key)
+		if cmp < 0 {
+			curr = curr.link[directionRight]
+			continue
+		}
+		path[top] = curr
+		top++
+		if cmp == 0 {
+			break
+		}
+		curr = curr.link[directionLeft]
+	}
+
+	for ; n > 0 && top > 0; n-- {
+		top--
+		node := path[top]
+		f(node.key, node.value)
+		for curr := node.link[directionRight]; curr != nil; curr = curr.link[directionLeft] {
+			path[top] = curr
+			top++
+		}
+	}
+}
+
+// Call f with the fast path of WithAppendOptimized enabled for Add.
+func (tree *Tree[K, V]) withAppendOptimized(f func() error) error {
+	if !tree.appendOpt {
+		// The cached rightmost node is not maintained without the option.
+		tree.appendOpt, tree.rightmost = true, nil
+		defer func() { tree.appendOpt = false }()
+	}
+	return f()
+}
+
+// Allocate a node from the node arena or allocator of the tree.
+func (tree *Tree[K, V]) newNode() *node[K, V] {
+	if tree.arena != nil {
+		return tree.arena.get()
+	}
+	if tree.allocator != nil {
+		return (*node[K, V])(tree.allocator.Get())
+	}
+	return &node[K, V]{}
+}
+
+// Return a node to the node arena or allocator of the tree. The release
+// function is called on the association of the node if non-nil.
+func (tree *Tree[K, V]) freeNode(n *node[K, V], release func(K, V)) {
+	if tree.arena != nil {
+		tree.arena.put(n, release)
+		return
+	}
+	if release != nil {
+		release(n.key, n.value)
+	}
+	if tree.allocator != nil {
+		// Clear node to avoid GC memory leaks as the node may be reused. Unless
+		// this is done this reachable object may keep other objects alive
+		// which could otherwise be garbage collected.
+		*n = node[K, V]{}
+		tree.allocator.Put((*Node[K, V])(n))
 	}
-	return zeroAssoc[K, V]()
 }
 
-// FindEqualOrGreater returns the association that match key or the immediately
-// greater association and true. The zero values of K and V and false is
-// returned if no assocation was found.
-func (tree *Tree[K, V]) FindEqualOrGreater(key K) (K, V, bool) {
-	var greater *node[K, V]
+// Return the node with the given zero based rank in ascending key order or nil
+// if rank is out of range.
+func (tree *Tree[K, V]) selectNode(rank int) *node[K, V] {
+	if rank < 0 || rank >= tree.length {
+		return nil
+	}
 
-	curr := tree.root
-	for curr != nil {
-		cmp := tree.compareKeys(curr.key, key)
-		if cmp == 0 {
-			break
-		}
-		if cmp > 0 {
-			greater = curr
+	node := tree.root
+	for {
+		left := node.link[directionLeft].subtreeSize()
+		switch {
+		case rank < left:
+			node = node.link[directionLeft]
+		case rank > left:
+			rank -= left + 1
+			node = node.link[directionRight]
+		default:
+			return node
 		}
-		curr = curr.link[directionOfBool(cmp < 0)]
 	}
-	if curr != nil {
-		return curr.key, curr.value, true
-	} else if greater != nil {
-		return greater.key, greater.value, true
+}
+
+// Build a balanced subtree from associations sorted in ascending key order.
+// Returns the root of the subtree and its height.
+func (tree *Tree[K, V]) buildBalanced(keys []K, values []V) (*node[K, V], int) {
+	nodes := make([]*node[K, V], len(keys))
+	for i := range keys {
+		nodes[i] = tree.newNode()
+		nodes[i].key, nodes[i].value = keys[i], values[i]
 	}
-	return zeroAssoc[K, V]()
+	return linkBalanced(nodes)
 }
 
-// FindLowest returns the association with the lowest key and true. The zero value
-// of K and V and false is returned if the tree is empty.
-func (tree *Tree[K, V]) FindLowest() (K, V, bool) {
-	return tree.edgeNode(directionLeft)
+// Make the tree, which must be empty, hold the associations of nodes sorted in
+// ascending key order. The nodes are linked into the tree if owned is true or
+// else copied to nodes allocated by the tree, updating nodes in place.
+func (tree *Tree[K, V]) adoptNodes(nodes []*node[K, V], owned bool) {
+	if !owned {
+		for i, n := range nodes {
+			c := tree.newNode()
+			c.key, c.value, c.seq = n.key, n.value, n.seq
+			nodes[i] = c
+		}
+	}
+	tree.root, _ = linkBalanced(nodes)
+	tree.length = len(nodes)
 }
 
-// FindHighest returns the association with the highest key and true. The zero value
-// of K and V and false is returned if the tree is empty.
-func (tree *Tree[K, V]) FindHighest() (K, V, bool) {
-	return tree.edgeNode(directionRight)
+// Append all nodes of the tree to dst in ascending key order.
+func (tree *Tree[K, V]) appendNodes(dst []*node[K, V]) []*node[K, V] {
+	tree.walkNodes(func(node *node[K, V]) bool {
+		dst = append(dst, node)
+		return true
+	})
+	return dst
 }
 
-// Apply calls the supplied function for each association in the tree.
-func (tree *Tree[K, V]) Apply(f func(K, V)) {
-	iter := tree.NewIterator()
-	for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
-		f(k, v)
+// Call f for each node of the tree in ascending key order until f returns
+// false. The tree iterator list is not used.
+func (tree *Tree[K, V]) walkNodes(f func(*node[K, V]) bool) {
+	var path [maxTreeHeight]*node[K, V]
+	var top int
+
+	for curr := tree.root; curr != nil; curr = curr.link[directionLeft] {
+		path[top] = curr
+		top++
+	}
+	for top > 0 {
+		top--
+		node := path[top]
+		if !f(node) {
+			return
+		}
+		for curr := node.link[directionRight]; curr != nil; curr = curr.link[directionLeft] {
+			path[top] = curr
+			top++
+		}
 	}
 }
 
-// NewIterator creates an iterator that advances from low to high key values.
-// Make sure to close the iterator by calling its Close method when done.
-func (tree *Tree[K, V]) NewIterator() *Iterator[K, V] {
-	return tree.iterator(directionRight)
+// NewChangedSinceIterator creates an iterator that advances from low to high key
+// values over associations added or modified after the sequence number since
+// (see Sequence). It requires the WithSequence option and visits all
+// associations of the tree to find modified ones. Associations moved to a new
+// tree, such as by SplitTopN, are not considered modified. Make sure to close
+// the iterator by calling its Close method when done using it unless it's
+// exhausted.
+func (tree *Tree[K, V]) NewChangedSinceIterator(since uint64) *ChangedSinceIterator[K, V] {
+	return &ChangedSinceIterator[K, V]{iter: tree.NewIterator(), since: since}
 }
 
-// NewReverseIterator creates an iterator that advances from high to low key
-// values. Make sure to close the iterator by calling its Close method when
-// done.
-func (tree *Tree[K, V]) NewReverseIterator() *Iterator[K, V] {
-	return tree.iterator(directionLeft)
+// NewShuffledIterator creates an iterator that yields every association of the
+// tree exactly once in a pseudo-random order drawn from rng. The same order is
+// produced for a tree with the same associations and an rng with the same
+// seed. All associations are copied when the iterator is created, which costs
+// memory proportional to the length of the tree; modifying the tree afterwards
+// does not affect the iterator.
+func (tree *Tree[K, V]) NewShuffledIterator(rng *rand.Rand) iter.PairIterator[K, V] {
+	assocs := make([]Assoc[K, V], 0, tree.length)
+	tree.walkNodes(func(n *node[K, V]) bool {
+		assocs = append(assocs, Assoc[K, V]{Key: n.key, Value: n.value})
+		return true
+	})
+	return &shuffledIterator[K, V]{assocs: assocs, rng: rng}
 }
 
 func (tree *Tree[K, V]) edgeNode(dir direction) (K, V, bool) {
@@ -377,22 +1753,176 @@ func (tree *Tree[K, V]) edgeNode(dir direction) (K, V, bool) {
 }
 
 func (tree *Tree[K, V]) iterator(dir direction) *Iterator[K, V] {
-	iter := &Iterator[K, V]{tree: tree, dir: dir}
-	iter.listNode.InitLinks().Value = iter
-
+	iter := tree.unlinkedIterator(dir)
 	if iter.buildPathStart() {
 		tree.iters.LinkNext(&iter.listNode)
 	}
 	return iter
 }
 
+// Create an iterator that is not positioned on any node. It behaves like a
+// closed iterator until positioned.
+func (tree *Tree[K, V]) unlinkedIterator(dir direction) *Iterator[K, V] {
+	// The path is sized for the current height of the tree and grows as needed.
+	iter := &Iterator[K, V]{tree: tree, path: make([]*node[K, V], 0, tree.Height()), dir: dir}
+	iter.listNode.InitLinks().Value = iter
+	return iter
+}
+
+// Position iterator on node which must be in the tree and link it to the tree
+// iterator list.
+func (iter *Iterator[K, V]) linkAt(node *node[K, V]) {
+	iter.curr = node
+	iter.buildPathCurr()
+	iter.tree.iters.LinkNext(&iter.listNode)
+}
+
+// PoolStats returns the number of nodes in use by the tree and the number of
+// nodes held by its node pool (see WithSyncPool) or arena (see WithArena). The
+// pooled count is shared by all trees using the same pool. It's an upper bound
+// as the garbage collector may drop pooled nodes without notice. Zero nodes are
+// pooled by trees using neither a pool nor an arena, including trees using
+// WithAllocator.
+func (tree *Tree[K, V]) PoolStats() (inUse, pooled int) {
+	if tree.arena != nil {
+		return tree.length, tree.arena.nfree
+	}
+	if pool, ok := tree.allocator.(*nodePool[K, V]); ok {
+		return tree.length, pool.pooled()
+	}
+	return tree.length, 0
+}
+
+// MostImbalancedKey returns the key of the node with the greatest height
+// difference between its left and right subtree and true. The difference never
+// exceeds one in a valid tree so this may be used when debugging a tree that
+// fails to Validate. Ties are resolved in favour of the lowest key. The zero
+// value of K and false is returned if the tree is empty.
+func (tree *Tree[K, V]) MostImbalancedKey() (K, bool) {
+	if tree.root == nil {
+		var zero K
+		return zero, false
+	}
+	_, node, _ := mostImbalanced(tree.root)
+	return node.key, true
+}
+
+// Return the height of the subtree rooted at root, its most imbalanced node and
+// the height difference of that node. Ties are resolved in favour of the
+// lowest key.
+func mostImbalanced[K, V any](root *node[K, V]) (height int, best *node[K, V], bestDiff int) {
+	if root == nil {
+		return 0, nil, -1
+	}
+
+	lheight, lbest, ldiff := mostImbalanced(root.link[directionLeft])
+	rheight, rbest, rdiff := mostImbalanced(root.link[directionRight])
+
+	best, bestDiff = lbest, ldiff
+	if diff := math.AbsSigned(lheight - rheight); diff > bestDiff {
+		best, bestDiff = root, diff
+	}
+	if rdiff > bestDiff {
+		best, bestDiff = rbest, rdiff
+	}
+	return math.MaxInteger(lheight, rheight) + 1, best, bestDiff
+}
+
+// TreeStats describe the shape of a tree. See Stats.
+type TreeStats struct {
+	Length       int // Number of associations
+	Height       int // Number of nodes on the longest path from the root to a leaf
+	Leaves       int // Number of nodes without children
+	MinLeafDepth int // Number of edges from the root to the shallowest leaf
+	MaxLeafDepth int // Number of edges from the root to the deepest leaf
+}
+
+// Stats returns statistics about the shape of the tree computed by visiting all
+// nodes once. The zero value is returned for an empty tree.
+func (tree *Tree[K, V]) Stats() TreeStats {
+	var stats TreeStats
+	if tree.root != nil {
+		stats.MinLeafDepth = tree.length
+		tree.statsNode(tree.root, 0, &stats)
+		stats.Height = stats.MaxLeafDepth + 1
+	}
+	return stats
+}
+
+func (tree *Tree[K, V]) statsNode(node *node[K, V], depth int, stats *TreeStats) {
+	stats.Length++
+	if node.link[directionLeft] == nil && node.link[directionRight] == nil {
+		stats.Leaves++
+		stats.MinLeafDepth = math.MinInteger(stats.MinLeafDepth, depth)
+		stats.MaxLeafDepth = math.MaxInteger(stats.MaxLeafDepth, depth)
+		return
+	}
+	for _, child := range node.link {
+		if child != nil {
+			tree.statsNode(child, depth+1, stats)
+		}
+	}
+}
+
+// SizeLeftOf returns the number of associations in the left subtree of the
+// node holding key and true. Zero and false is returned if no association was
+// found. It's intended for inspecting the shape of the tree; see Rank for the
+// number of keys less than key.
+func (tree *Tree[K, V]) SizeLeftOf(key K) (int, bool) {
+	if node := tree.findNode(key); node != nil {
+		return node.link[directionLeft].subtreeSize(), true
+	}
+	return 0, false
+}
+
+// String returns the associations of the tree in ascending key order
+// formatted like {1:a 2:b 3:c} using the default formats of fmt.
+func (tree *Tree[K, V]) String() string {
+	var b strings.Builder
+	b.WriteByte('{')
+	tree.walkNodes(func(n *node[K, V]) bool {
+		if b.Len() > 1 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%v:%v", n.key, n.value)
+		return true
+	})
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Dump writes the structure of the tree to w for debugging. The tree is drawn
+// sideways with the root to the left and high keys on top. Each node is written
+// on its own line indented by its depth and followed by its balance factor,
+// like "2:b [0]".
+func (tree *Tree[K, V]) Dump(w io.Writer) error {
+	return dumpNode(w, tree.root, 0)
+}
+
+func dumpNode[K, V any](w io.Writer, node *node[K, V], depth int) error {
+	if node == nil {
+		return nil
+	}
+	if err := dumpNode(w, node.link[directionRight], depth+1); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%s%v:%v [%d]\n", strings.Repeat("    ", depth), node.key, node.value, node.balance); err != nil {
+		return err
+	}
+	return dumpNode(w, node.link[directionLeft], depth+1)
+}
+
 // Validate tree invariants. A valid tree should always be balanced and sorted.
+// Balanced also covers the bookkeeping of subtree sizes used by Select.
 func (tree *Tree[K, V]) Validate() (balanced, sorted bool) {
 	balanced = true
 	sorted = true
 
 	if tree.root != nil {
 		tree.validateNode(tree.root, &balanced, &sorted, 0)
+		if tree.root.size != tree.length {
+			balanced = false
+		}
 	}
 	return
 }
@@ -416,6 +1946,9 @@ func (tree *Tree[K, V]) validateNode(node *node[K, V], rvBalanced, rvSorted *boo
 	if math.AbsSigned(depthLink[directionLeft]-depthLink[directionRight]) > 1 {
 		*rvBalanced = false
 	}
+	if node.size != 1+node.link[directionLeft].subtreeSize()+node.link[directionRight].subtreeSize() {
+		*rvBalanced = false
+	}
 
 	return math.MaxInteger(depthLink[directionLeft], depthLink[directionRight])
 }
@@ -429,10 +1962,12 @@ type Iterator[K, V any] struct {
 	listNode list.Node[*Iterator[K, V]] // List node to make it linkable to tree iterator list
 	tree     *Tree[K, V]                // Tree iterator belongs to
 	curr     *node[K, V]                // Current node
-	path     [maxTreeHeight]*node[K, V] // Traversal path
-	top      int                        // Top of stack
+	path     []*node[K, V]              // Traversal path
 	dir      direction                  // Direction of movement
 	update   bool                       // Update path before moving
+	bounded  bool                       // Stay within start and bound keys
+	start    K                          // First key to visit if bounded
+	bound    K                          // Last key to visit if bounded
 }
 
 // Next returns the next association from the iterator. The zero values of K and
@@ -440,8 +1975,71 @@ type Iterator[K, V any] struct {
 // (such as when all associations has been visited). Close has been called when
 // false is returned.
 func (iter *Iterator[K, V]) Next() (K, V, bool) {
-	if !iter.listNode.IsLinked() {
-		return zeroAssoc[K, V]()
+	return iter.nextNode().assoc()
+}
+
+// Seek repositions the iterator so that the next call to Next returns the
+// association for key or, if there is none, the association with the nearest
+// key following key in the direction of the iterator. The iterator is closed if
+// there is no such association. Closed iterators are not affected. A bounded
+// iterator, such as one created by NewRangeIterator, stays within its range; a
+// key preceding the start of the range in the direction of the iterator seeks
+// to the start of the range and the iterator stops after the end of the range.
+func (iter *Iterator[K, V]) Seek(key K) {
+	if iter.curr == nil {
+		return
+	}
+	if iter.bounded {
+		if cmp := iter.tree.compareKeys(key, iter.start); cmp != 0 && directionOfBool(cmp > 0) != iter.dir {
+			key = iter.start
+		}
+	}
+	iter.update = false
+	if !iter.buildPathNear(key) {
+		iter.Close()
+	}
+}
+
+// Peek returns the association that the next call to Next returns without
+// advancing the iterator. The zero values of K and V and false is returned if
+// the iterator is not positioned on any association, in which case Close has
+// been called.
+func (iter *Iterator[K, V]) Peek() (K, V, bool) {
+	return iter.currNode().assoc()
+}
+
+// Clone returns an iterator positioned on the same association and moving in
+// the same direction as iter that may be advanced independently. The clone is
+// updated by tree modifications unless iter was created by
+// NewUntrackedIterator. Make sure to close the clone by calling its Close
+// method when done using it unless it's exhausted. The clone of a closed
+// iterator is closed.
+func (iter *Iterator[K, V]) Clone() *Iterator[K, V] {
+	clone := new(Iterator[K, V])
+	*clone = *iter
+	clone.path = append([]*node[K, V](nil), iter.path...)
+	clone.listNode.InitLinks().Value = clone
+	if iter.listNode.IsLinked() {
+		iter.tree.iters.LinkNext(&clone.listNode)
+	}
+	return clone
+}
+
+// Return the current node and advance the iterator. Returns nil if the
+// iterator is not positioned on any node.
+func (iter *Iterator[K, V]) nextNode() *node[K, V] {
+	node := iter.currNode()
+	if node != nil && !iter.advance() {
+		iter.Close()
+	}
+	return node
+}
+
+// Return the current node without advancing the iterator. Returns nil if the
+// iterator is not positioned on any node.
+func (iter *Iterator[K, V]) currNode() *node[K, V] {
+	if iter.curr == nil {
+		return nil
 	}
 
 	if iter.update {
@@ -449,11 +2047,13 @@ func (iter *Iterator[K, V]) Next() (K, V, bool) {
 		iter.update = false
 	}
 
-	key, value := iter.curr.key, iter.curr.value
-	if !iter.advance() {
-		iter.Close()
+	if iter.bounded {
+		if cmp := iter.tree.compareKeys(iter.curr.key, iter.bound); cmp != 0 && directionOfBool(cmp > 0) == iter.dir {
+			iter.Close()
+			return nil
+		}
 	}
-	return key, value, true
+	return iter.curr
 }
 
 // Close invalidates the iterator and removes its reference from the tree it's
@@ -464,9 +2064,7 @@ func (iter *Iterator[K, V]) Close() {
 	// Clear pointers to avoid GC memory leaks.
 	iter.tree = nil
 	iter.curr = nil
-	for i := range iter.path {
-		iter.path[i] = nil
-	}
+	iter.path = nil
 }
 
 // Move iterator according to its recorded direction and report whether it fell
@@ -476,28 +2074,25 @@ func (iter *Iterator[K, V]) advance() bool {
 
 	if iter.curr.link[dir] != nil {
 		// Continue down this branch
-		iter.path[iter.top] = iter.curr
+		iter.path = append(iter.path, iter.curr)
 		iter.curr = iter.curr.link[dir]
-		iter.top++
 
 		for iter.curr.link[dir.other()] != nil {
-			iter.path[iter.top] = iter.curr
+			iter.path = append(iter.path, iter.curr)
 			iter.curr = iter.curr.link[dir.other()]
-			iter.top++
 		}
 	} else {
 		// Move to the next branch
 		var last *node[K, V]
 
 		for {
-			if iter.top == 0 {
+			if len(iter.path) == 0 {
 				iter.curr = nil
 				break
 			}
 
-			iter.top--
 			last = iter.curr
-			iter.curr = iter.path[iter.top]
+			iter.pop()
 
 			if last != iter.curr.link[dir] {
 				break
@@ -508,19 +2103,26 @@ func (iter *Iterator[K, V]) advance() bool {
 	return iter.curr != nil
 }
 
+// Move iterator to the node on top of the path, removing it from the path.
+func (iter *Iterator[K, V]) pop() {
+	top := len(iter.path) - 1
+	iter.curr = iter.path[top]
+	iter.path[top] = nil
+	iter.path = iter.path[:top]
+}
+
 // Build path to first or last association depending on iterator direction and
 // report if it was successful.
 func (iter *Iterator[K, V]) buildPathStart() bool {
 	dir := iter.dir.other()
 
 	iter.curr = iter.tree.root
-	iter.top = 0
+	iter.path = iter.path[:0]
 
 	if iter.curr != nil {
 		for iter.curr.link[dir] != nil {
-			iter.path[iter.top] = iter.curr
+			iter.path = append(iter.path, iter.curr)
 			iter.curr = iter.curr.link[dir]
-			iter.top++
 		}
 		return true
 	}
@@ -533,48 +2135,152 @@ func (iter *Iterator[K, V]) buildPathCurr() {
 	key := iter.curr.key
 
 	iter.curr = tree.root
-	iter.top = 0
+	iter.path = iter.path[:0]
 
 	for cmp := tree.compareKeys(iter.curr.key, key); cmp != 0; cmp = tree.compareKeys(iter.curr.key, key) {
-		iter.path[iter.top] = iter.curr
+		iter.path = append(iter.path, iter.curr)
 		iter.curr = iter.curr.link[directionOfBool(cmp < 0)]
-		iter.top++
 	}
 }
 
 // Build path to node next to current node and report whether it fell over the
 // edge.
 func (iter *Iterator[K, V]) buildPathNext() bool {
+	return iter.buildPathNear(iter.curr.key)
+}
+
+// Build path to the node holding key or the node nearest to key in the
+// iterator direction and report whether it fell over the edge.
+func (iter *Iterator[K, V]) buildPathNear(key K) bool {
 	tree := iter.tree
-	key := iter.curr.key
 
 	var match *node[K, V]
 
 	iter.curr = tree.root
-	iter.top = 0
+	iter.path = iter.path[:0]
 
 	for iter.curr != nil {
-		dir := directionOfBool(tree.compareKeys(iter.curr.key, key) < 0)
+		cmp := tree.compareKeys(iter.curr.key, key)
+		if cmp == 0 {
+			return true
+		}
+		dir := directionOfBool(cmp < 0)
 		if dir != iter.dir {
 			// This node matched the direction criteria.
 			match = iter.curr
 		}
-		iter.path[iter.top] = iter.curr
+		iter.path = append(iter.path, iter.curr)
 		iter.curr = iter.curr.link[dir]
-		iter.top++
 	}
 
 	if match != nil {
 		// Wind back path to best match.
 		for iter.curr != match {
-			iter.top--
-			iter.curr = iter.path[iter.top]
+			iter.pop()
 		}
 		return true
 	}
 	return false
 }
 
+/******************************************************************************
+ * Gap Iterator
+ *****************************************************************************/
+
+// GapIterator reports gaps in the key sequence of a tree, see NewGapIterator.
+type GapIterator[K, V any] struct {
+	iter        *Iterator[K, V]
+	compareKeys math.Comparator[K]
+	next        func(K) K
+	prev        K    // Previously visited key
+	started     bool // Set once the first key has been visited
+}
+
+// Next returns the next gap as the expected key and the actual key that follows
+// the gap. The zero values of K and false is returned when there are no more
+// gaps.
+func (gap *GapIterator[K, V]) Next() (K, K, bool) {
+	if !gap.started {
+		k, _, ok := gap.iter.Next()
+		if !ok {
+			return zeroAssoc[K, K]()
+		}
+		gap.prev, gap.started = k, true
+	}
+
+	for k, _, ok := gap.iter.Next(); ok; k, _, ok = gap.iter.Next() {
+		expected := gap.next(gap.prev)
+		gap.prev = k
+		if gap.compareKeys(expected, k) != 0 {
+			return expected, k, true
+		}
+	}
+	return zeroAssoc[K, K]()
+}
+
+// Close invalidates the iterator and removes its reference from the tree it's
+// associated with. It's safe to call the Next method on closed iterators.
+func (gap *GapIterator[K, V]) Close() {
+	gap.iter.Close()
+}
+
+/******************************************************************************
+ * Changed Since Iterator
+ *****************************************************************************/
+
+// ChangedSinceIterator visits associations modified after a sequence number,
+// see NewChangedSinceIterator.
+type ChangedSinceIterator[K, V any] struct {
+	iter  *Iterator[K, V]
+	since uint64
+}
+
+// Next returns the next association modified after the sequence number of the
+// iterator. The zero values of K and V and false is returned when there are no
+// more such associations.
+func (changed *ChangedSinceIterator[K, V]) Next() (K, V, bool) {
+	for node := changed.iter.nextNode(); node != nil; node = changed.iter.nextNode() {
+		if node.seq > changed.since {
+			return node.key, node.value, true
+		}
+	}
+	return zeroAssoc[K, V]()
+}
+
+// Close invalidates the iterator and removes its reference from the tree it's
+// associated with. It's safe to call the Next method on closed iterators.
+func (changed *ChangedSinceIterator[K, V]) Close() {
+	changed.iter.Close()
+}
+
+/******************************************************************************
+ * Shuffled Iterator
+ *****************************************************************************/
+
+type shuffledIterator[K, V any] struct {
+	assocs []Assoc[K, V] // Associations not yet visited
+	rng    *rand.Rand
+}
+
+// Next returns a randomly chosen association among those not yet visited. The
+// zero values of K and V and false is returned when all associations have been
+// visited.
+func (shuffled *shuffledIterator[K, V]) Next() (K, V, bool) {
+	n := len(shuffled.assocs)
+	if n == 0 {
+		return zeroAssoc[K, V]()
+	}
+
+	// Incremental Fisher-Yates shuffle from the end of the slice.
+	i := shuffled.rng.Intn(n)
+	a := shuffled.assocs
+	a[i], a[n-1] = a[n-1], a[i]
+	pick := a[n-1]
+	a[n-1] = Assoc[K, V]{}
+	shuffled.assocs = a[:n-1]
+	return pick.Key, pick.Value, true
+}
+
 /******************************************************************************
  * Tree Options
  *****************************************************************************/
@@ -588,7 +2294,105 @@ type TreeOption[K, V any] func(*Tree[K, V])
 func WithSyncPool[K, V any]() TreeOption[K, V] {
 	nodePool := newNodePool[K, V]()
 	return func(tree *Tree[K, V]) {
-		tree.nodePool = nodePool
+		tree.allocator = nodePool
+		tree.arena = nil
+	}
+}
+
+// WithArena creates a tree option to allocate nodes from contiguous slabs of
+// memory owned by the tree. It may improve the performance of iterating over
+// large trees due to better cache locality. Removed nodes are reused by the
+// same tree but can not be returned to a shared pool; all nodes are reclaimed
+// by Clear. A slab is kept alive by the garbage collector as long as any of its
+// nodes are in use. This option replaces any WithSyncPool option and vice
+// versa.
+func WithArena[K, V any]() TreeOption[K, V] {
+	return func(tree *Tree[K, V]) {
+		tree.arena = &nodeArena[K, V]{}
+		tree.allocator = nil
+	}
+}
+
+// WithAllocator creates a tree option to allocate and recycle nodes using
+// allocator. The allocator may be used by multiple trees, in which case it must
+// be safe for concurrent use if the trees are used by multiple go routines.
+// This option replaces any WithSyncPool or WithArena option and vice versa.
+func WithAllocator[K, V any](allocator Allocator[K, V]) TreeOption[K, V] {
+	return func(tree *Tree[K, V]) {
+		tree.allocator = allocator
+		tree.arena = nil
+	}
+}
+
+// WithMaxSize creates a tree option that limits the number of associations in
+// a tree to n. When Add is about to insert a new association into a full tree,
+// an existing association is removed before the new one is inserted. The key
+// to remove is chosen by calling evict with the association being added.
+// Overwriting an existing association does not cause any eviction. The
+// association with the lowest key is evicted if evict is nil, reports false or
+// returns a key that is not in the tree. Iterators are updated as if Remove had
+// been called. Panics if n is less than one.
+func WithMaxSize[K, V any](n int, evict func(K, V) (victimKey K, ok bool)) TreeOption[K, V] {
+	if n < 1 {
+		panic("avltree: max size must be positive")
+	}
+	return func(tree *Tree[K, V]) {
+		tree.maxSize = n
+		tree.evict = evict
+		tree.evictOldest = false
+		tree.onEvict = nil
+	}
+}
+
+// WithMaxSizeFIFO creates a tree option that limits the number of associations
+// in a tree to n like WithMaxSize but evicts the association that was least
+// recently added or overwritten, regardless of its key. The evicted association
+// is passed to onEvict if non-nil. Finding the association to evict visits all
+// associations of the tree, for a cost of O(n) per eviction. The option implies
+// WithSequence and replaces any WithMaxSize option and vice versa. Panics if n
+// is less than one.
+func WithMaxSizeFIFO[K, V any](n int, onEvict func(K, V)) TreeOption[K, V] {
+	if n < 1 {
+		panic("avltree: max size must be positive")
+	}
+	return func(tree *Tree[K, V]) {
+		tree.maxSize = n
+		tree.evict = nil
+		tree.evictOldest = true
+		tree.onEvict = onEvict
+		tree.sequenced = true
+	}
+}
+
+// WithSequence creates a tree option to stamp associations with an increasing
+// sequence number each time they are added or modified, including by
+// UpdateKey. The sequence number of
+// the last modification is returned by the Sequence method of the tree. See
+// NewChangedSinceIterator.
+func WithSequence[K, V any]() TreeOption[K, V] {
+	return func(tree *Tree[K, V]) {
+		tree.sequenced = true
+	}
+}
+
+// WithAppendOptimized creates a tree option to speed up adding associations
+// with keys higher than any key in the tree. The node holding the highest key
+// is cached and such keys are attached along the right edge of the tree using
+// a single key comparison instead of comparing against each key on the path
+// from the root. Other keys are added as usual.
+func WithAppendOptimized[K, V any]() TreeOption[K, V] {
+	return func(tree *Tree[K, V]) {
+		tree.appendOpt = true
+	}
+}
+
+// WithKeyInterner creates a tree option that canonicalize keys passed to Add
+// by calling intern with the key and storing the returned key instead. This
+// lets equal keys share a single backing object. The interned key must compare
+// equal to the original key for the ordering invariant of the tree to hold.
+func WithKeyInterner[K, V any](intern func(K) K) TreeOption[K, V] {
+	return func(tree *Tree[K, V]) {
+		tree.internKey = intern
 	}
 }
 
@@ -599,23 +2403,57 @@ func WithSyncPool[K, V any]() TreeOption[K, V] {
 type node[K, V any] struct {
 	link    [2]*node[K, V] //Left and right links.
 	balance int            // Balance factor
+	size    int            // Number of nodes in subtree rooted at node
+	seq     uint64         // Sequence number of last modification
 	key     K
 	value   V
 }
 
+// Return the association held by node and true. The zero values of K and V and
+// false is returned if node is nil.
+func (node *node[K, V]) assoc() (K, V, bool) {
+	if node != nil {
+		return node.key, node.value, true
+	}
+	return zeroAssoc[K, V]()
+}
+
+// Link nodes sorted in ascending key order into a balanced subtree. Returns the
+// root of the subtree and its height.
+func linkBalanced[K, V any](nodes []*node[K, V]) (*node[K, V], int) {
+	if len(nodes) == 0 {
+		return nil, 0
+	}
+
+	mid := len(nodes) / 2
+	root := nodes[mid]
+
+	var height [2]int
+	root.link[directionLeft], height[directionLeft] = linkBalanced(nodes[:mid])
+	root.link[directionRight], height[directionRight] = linkBalanced(nodes[mid+1:])
+	root.balance = height[directionRight] - height[directionLeft]
+	root.size = len(nodes)
+
+	return root, math.MaxInteger(height[directionLeft], height[directionRight]) + 1
+}
+
 // Two way single rotation
 func (root *node[K, V]) singleRotation(dir direction) *node[K, V] {
 	odir := dir.other()
 	save := root.link[odir]
 	root.link[odir] = save.link[dir]
 	save.link[dir] = root
+
+	save.size = root.size
+	root.updateSize()
 	return save
 }
 
 // Two way double rotation.
 func (root *node[K, V]) doubleRotation(dir direction) *node[K, V] {
 	odir := dir.other()
-	save := root.link[odir].link[dir]
+	child := root.link[odir]
+	save := child.link[dir]
 	root.link[odir].link[dir] = save.link[odir]
 	save.link[odir] = root.link[odir]
 	root.link[odir] = save
@@ -623,9 +2461,26 @@ func (root *node[K, V]) doubleRotation(dir direction) *node[K, V] {
 	save = root.link[odir]
 	root.link[odir] = save.link[dir]
 	save.link[dir] = root
+
+	save.size = root.size
+	root.updateSize()
+	child.updateSize()
 	return save
 }
 
+// Recompute the subtree size of node from the sizes of its children.
+func (node *node[K, V]) updateSize() {
+	node.size = 1 + node.link[directionLeft].subtreeSize() + node.link[directionRight].subtreeSize()
+}
+
+// Return the number of nodes in the subtree rooted at node, which may be nil.
+func (node *node[K, V]) subtreeSize() int {
+	if node == nil {
+		return 0
+	}
+	return node.size
+}
+
 // Adjust balance before double rotation.
 func (root *node[K, V]) adjustBalance(dir direction, bal int) {
 	n1 := root.link[dir]
@@ -689,49 +2544,132 @@ func (root *node[K, V]) removeBalance(dir direction) (rnode *node[K, V], done bo
  * Node pool
  *****************************************************************************/
 
+// Allocator allocates and recycles the nodes of trees, see WithAllocator. Get
+// must return a zeroed node that is not in use. Nodes passed to Put are no
+// longer in use by the tree and have been zeroed.
+type Allocator[K, V any] interface {
+	Get() *Node[K, V]
+	Put(node *Node[K, V])
+}
+
+// Node is a tree node as seen by an Allocator. Its content is private to the
+// tree. Allocators may allocate nodes in any way, such as from slices of nodes
+// for locality.
+type Node[K, V any] node[K, V]
+
 // A type safe wrapper around sync.Pool.
 type nodePool[K, V any] struct {
+	// Counters are accessed atomically and kept first for 64-bit alignment.
+	puts int64 // Nodes put in pool
+	gets int64 // Nodes taken from pool
+	news int64 // Nodes allocated by pool
 	pool sync.Pool
 }
 
 // newNodePool allocates a new node pool holding nodes with keys of type K and
 // values of type V.
 func newNodePool[K, V any]() *nodePool[K, V] {
-	return &nodePool[K, V]{pool: sync.Pool{New: func() any { return new(node[K, V]) }}}
+	pool := &nodePool[K, V]{}
+	pool.pool.New = func() any {
+		atomic.AddInt64(&pool.news, 1)
+		return new(Node[K, V])
+	}
+	return pool
+}
+
+// Get node from pool.
+func (pool *nodePool[K, V]) Get() *Node[K, V] {
+	atomic.AddInt64(&pool.gets, 1)
+	return pool.pool.Get().(*Node[K, V])
 }
 
-// Get node from pool. The pool may be nil in which case a normal allocation is
-// performed.
-func (pool *nodePool[K, V]) get() *node[K, V] {
-	if pool != nil {
-		return pool.pool.Get().(*node[K, V])
+// Return the number of nodes put in the pool that has not been reused. The pool
+// may be nil in which case zero is returned.
+func (pool *nodePool[K, V]) pooled() int {
+	if pool == nil {
+		return 0
 	}
-	return &node[K, V]{}
+	reused := atomic.LoadInt64(&pool.gets) - atomic.LoadInt64(&pool.news)
+	return int(atomic.LoadInt64(&pool.puts) - reused)
 }
 
-// Return node to pool. The pool may be nil in which case the release function
-// is called but no other action is performed.
-func (pool *nodePool[K, V]) put(node *node[K, V], release func(K, V)) {
-	if release != nil {
-		release(node.key, node.value)
+// Put node in pool for reuse.
+func (pool *nodePool[K, V]) Put(node *Node[K, V]) {
+	atomic.AddInt64(&pool.puts, 1)
+	pool.pool.Put(node)
+}
+
+/******************************************************************************
+ * Node arena
+ *****************************************************************************/
+
+// Slab size limits of node arenas measured in nodes.
+const (
+	minArenaSlab = 16
+	maxArenaSlab = 1024
+)
+
+// Allocator of nodes from contiguous slabs of memory. Freed nodes are kept in a
+// free list for reuse. It's not safe for concurrent use.
+type nodeArena[K, V any] struct {
+	slab  []node[K, V] // Current slab, nodes beyond its length are unused
+	free  *node[K, V]  // Free list linked by right links
+	nfree int          // Length of free list
+}
+
+// Return a new empty arena if arena is non-nil. This is used to give trees
+// derived from a tree using an arena their own arena.
+func (arena *nodeArena[K, V]) fresh() *nodeArena[K, V] {
+	if arena != nil {
+		return &nodeArena[K, V]{}
+	}
+	return nil
+}
+
+// Get node from arena.
+func (arena *nodeArena[K, V]) get() *node[K, V] {
+	if n := arena.free; n != nil {
+		arena.free = n.link[directionRight]
+		arena.nfree--
+		n.link[directionRight] = nil
+		return n
 	}
 
-	if pool != nil {
-		// Clear pointers to avoid GC memory leaks as the node will be put in a
-		// pool for reuse. Unless this is done this reachable object may keep
-		// other objects alive which could otherwise be garbage collected.
-		node.link[directionLeft] = nil
-		node.link[directionRight] = nil
+	if len(arena.slab) == cap(arena.slab) {
+		size := math.MinInteger(math.MaxInteger(2*cap(arena.slab), minArenaSlab), maxArenaSlab)
+		arena.slab = make([]node[K, V], 0, size)
+	}
+	arena.slab = arena.slab[:len(arena.slab)+1]
+	return &arena.slab[len(arena.slab)-1]
+}
+
+// Return node to arena. The release function is called if non-nil.
+func (arena *nodeArena[K, V]) put(n *node[K, V], release func(K, V)) {
+	if release != nil {
+		release(n.key, n.value)
+	}
 
-		// Keys and values can also be or contain pointers.
-		node.key, _ = zeroValue[K]()
-		node.value, _ = zeroValue[V]()
+	// Clear node to avoid GC memory leaks while in the free list.
+	*n = node[K, V]{}
 
-		// Clear balance before putting node in pool.
-		node.balance = 0
+	n.link[directionRight] = arena.free
+	arena.free = n
+	arena.nfree++
+}
 
-		pool.pool.Put(node)
+// Reclaim all nodes of the arena. The arena may be nil in which case no action
+// is performed. Only the current slab is reused as nodes in the free list may
+// belong to older slabs.
+func (arena *nodeArena[K, V]) reset() {
+	if arena == nil {
+		return
+	}
+	for i := range arena.slab {
+		arena.slab[i] = node[K, V]{}
 	}
+	arena.slab = arena.slab[:0]
+	arena.free = nil
+	arena.nfree = 0
 }
 
 /******************************************************************************
@@ -1,7 +1,13 @@
 package avltree_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/johan-bolmsjo/gods/v2/avltree"
@@ -22,7 +28,21 @@ type assoc struct {
 // Brute force test of tree rotations triggered by inserting elements.
 // Tree invariants are validated after each operation.
 func TestInvariantsPermuteInsert(t *testing.T) {
-	tree := newTree(nil, avltree.WithSyncPool[keyType, valType]())
+	for _, td := range []struct {
+		name   string
+		option treeOptionType
+	}{
+		{"SyncPool", avltree.WithSyncPool[keyType, valType]()},
+		{"Arena", avltree.WithArena[keyType, valType]()},
+	} {
+		t.Run(td.name, func(t *testing.T) {
+			testInvariantsPermuteInsert(t, td.option)
+		})
+	}
+}
+
+func testInvariantsPermuteInsert(t *testing.T, option treeOptionType) {
+	tree := newTree(nil, option)
 	src := someKeys{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
 	var dst someKeys
 	alen := len(src)
@@ -49,7 +69,21 @@ func TestInvariantsPermuteInsert(t *testing.T) {
 // Brute force test of tree rotations triggered by removing elements.
 // Tree invariants are validated after each operation.
 func TestInvariantsPermuteRemove(t *testing.T) {
-	tree := newTree(nil, avltree.WithSyncPool[keyType, valType]())
+	for _, td := range []struct {
+		name   string
+		option treeOptionType
+	}{
+		{"SyncPool", avltree.WithSyncPool[keyType, valType]()},
+		{"Arena", avltree.WithArena[keyType, valType]()},
+	} {
+		t.Run(td.name, func(t *testing.T) {
+			testInvariantsPermuteRemove(t, td.option)
+		})
+	}
+}
+
+func testInvariantsPermuteRemove(t *testing.T, option treeOptionType) {
+	tree := newTree(nil, option)
 	src := someKeys{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
 	var dst someKeys
 	alen := len(src)
@@ -81,6 +115,21 @@ func TestInvariantsPermuteRemove(t *testing.T) {
 	t.Logf("%d remove sequences tested", seq)
 }
 
+// Descending trees should order keys from high to low.
+func TestNewOrderedDescending(t *testing.T) {
+	tree := bulkInsert(avltree.NewOrderedDescending[keyType, valType](), []keyType{2, 1, 3})
+	want := []keyType{3, 2, 1}
+	if got := getIterSeq(tree.NewIterator()); !checkIterSeq(got, want) {
+		t.Fatalf("got sequence %v; want %v", got, want)
+	}
+	if got, want := kvResultString(tree.FindLowest()), kvResultString(3, 3, true); got != want {
+		t.Fatalf("tree.FindLowest() = %v; want %v", got, want)
+	}
+	if balanced, sorted := tree.Validate(); !balanced || !sorted {
+		t.Fatalf("Invalid tree invariant: balanced=%v, sorted=%v", balanced, sorted)
+	}
+}
+
 // Adding a key that already exist should overwrite the existing association.
 func TestAddExisting(t *testing.T) {
 	tree := newTree(nil)
@@ -104,6 +153,310 @@ func TestAddExisting(t *testing.T) {
 	}
 }
 
+// AddReturningOld should return the overwritten value.
+func TestAddReturningOld(t *testing.T) {
+	tree := newTree(nil)
+	if got, want := vResultString(tree.AddReturningOld(1, 10)), "0,false"; got != want {
+		t.Fatalf("tree.AddReturningOld(1, 10) = %s; want %s", got, want)
+	}
+	if got, want := vResultString(tree.AddReturningOld(1, 20)), "10,true"; got != want {
+		t.Fatalf("tree.AddReturningOld(1, 20) = %s; want %s", got, want)
+	}
+	if got, want := vResultString(tree.Find(1)), "20,true"; got != want {
+		t.Fatalf("tree.Find(1) = %s; want %s", got, want)
+	}
+}
+
+// MergeSorted should resolve existing keys and insert new ones.
+func TestMergeSorted(t *testing.T) {
+	sum := func(_ keyType, existing, incoming valType) valType { return existing + incoming }
+	batch := func(keys ...keyType) (pairs []avltree.Assoc[keyType, valType]) {
+		for _, k := range keys {
+			pairs = append(pairs, avltree.Assoc[keyType, valType]{Key: k, Value: valType(k)})
+		}
+		return
+	}
+
+	testData := []struct {
+		name    string
+		keys    []keyType
+		pairs   []avltree.Assoc[keyType, valType]
+		resolve func(keyType, valType, valType) valType
+		want    string
+	}{
+		{"Empty", nil, nil, sum, "[]"},
+		{"IntoEmpty", nil, batch(1, 2, 2), sum, "[{1 1} {2 4}]"},
+		{"UpdateOnly", []keyType{1, 2, 3}, batch(1, 3), sum, "[{1 2} {2 2} {3 6}]"},
+		{"Mixed", []keyType{1, 3, 5}, batch(0, 1, 1, 4, 6, 6), sum, "[{0 0} {1 3} {3 3} {4 4} {5 5} {6 12}]"},
+		{"Overwrite", []keyType{1, 3}, batch(1, 2, 3), nil, "[{1 1} {2 2} {3 3}]"},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			tree := newTree(td.keys)
+			tree.MergeSorted(td.pairs, td.resolve)
+			if balanced, sorted := tree.Validate(); !balanced || !sorted {
+				t.Fatalf("Invalid tree invariant: balanced=%v, sorted=%v", balanced, sorted)
+			}
+			got := getIterSeq(tree.NewIterator())
+			if s := fmt.Sprint(got); s != td.want {
+				t.Fatalf("got sequence %v; want %v", s, td.want)
+			}
+			if tree.Length() != len(got) {
+				t.Fatalf("tree.Length() = %d; want %d", tree.Length(), len(got))
+			}
+		})
+	}
+
+	t.Run("IteratorUpdate", func(t *testing.T) {
+		tree := newTree([]keyType{1, 3, 5, 7, 9})
+		iter := tree.NewIterator()
+		iter.Next()
+		tree.MergeSorted(batch(2, 4, 6, 8, 10), nil)
+		want := []keyType{3, 4, 5, 6, 7, 8, 9, 10}
+		if got := getIterSeq(iter); !checkIterSeq(got, want) {
+			t.Fatalf("got sequence %v; want %v", got, want)
+		}
+	})
+
+	t.Run("MaxSize", func(t *testing.T) {
+		tree := newTree([]keyType{1, 2, 3}, avltree.WithMaxSize[keyType, valType](3, nil))
+		tree.MergeSorted(batch(2, 4), sum)
+		want := "[{2 4} {3 3} {4 4}]"
+		if got := fmt.Sprint(getIterSeq(tree.NewIterator())); got != want {
+			t.Fatalf("got sequence %v; want %v", got, want)
+		}
+	})
+
+	t.Run("Unsorted", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r != "avltree: batch is not sorted" {
+				t.Fatalf("recover() = %v; want unsorted batch panic", r)
+			}
+		}()
+		newTree(nil).MergeSorted(batch(2, 1), nil)
+	})
+}
+
+// LoadSorted should rebuild the tree from sorted input.
+func TestLoadSorted(t *testing.T) {
+	pairs := func(keys ...keyType) (pairs []avltree.Assoc[keyType, valType]) {
+		for _, k := range keys {
+			pairs = append(pairs, avltree.Assoc[keyType, valType]{Key: k, Value: valType(k)})
+		}
+		return
+	}
+	sum := func(existing, incoming valType) valType { return existing + incoming }
+
+	tree := newTree([]keyType{10, 20})
+	iter := tree.NewIterator()
+	if err := tree.LoadSorted(pairs(1, 2, 2, 3, 4, 5, 6, 7), sum); err != nil {
+		t.Fatalf("tree.LoadSorted() = %v; want nil", err)
+	}
+	if balanced, sorted := tree.Validate(); !balanced || !sorted {
+		t.Fatalf("Invalid tree invariant: balanced=%v, sorted=%v", balanced, sorted)
+	}
+	want := "[{1 1} {2 4} {3 3} {4 4} {5 5} {6 6} {7 7}]"
+	if got := fmt.Sprint(getIterSeq(tree.NewIterator())); got != want || tree.Length() != 7 {
+		t.Fatalf("got sequence %v (length %d); want %v (length 7)", got, tree.Length(), want)
+	}
+	if got, want := kvResultString(iter.Next()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("iter.Next() = %v; want %v", got, want)
+	}
+
+	if err := tree.LoadSorted(pairs(1, 3, 2), nil); err != avltree.ErrUnsorted {
+		t.Fatalf("tree.LoadSorted() = %v; want %v", err, avltree.ErrUnsorted)
+	}
+	if got := fmt.Sprint(getIterSeq(tree.NewIterator())); got != want {
+		t.Fatalf("got sequence %v after failed load; want %v", got, want)
+	}
+
+	if err := tree.LoadSorted(nil, nil); err != nil || tree.Length() != 0 {
+		t.Fatalf("tree.LoadSorted(nil) = %v (length %d); want nil (length 0)", err, tree.Length())
+	}
+}
+
+// UpdateKey should only replace keys that compare equal.
+func TestUpdateKey(t *testing.T) {
+	type compositeKey struct {
+		id   int
+		meta string
+	}
+	compare := func(lhs, rhs compositeKey) int { return math.CompareOrdered(lhs.id, rhs.id) }
+	tree := avltree.New[compositeKey, int](compare)
+	tree.Add(compositeKey{1, "old"}, 1)
+
+	testData := []struct {
+		key, newKey compositeKey
+		want        bool
+		wantKey     compositeKey
+	}{
+		{compositeKey{1, ""}, compositeKey{2, "reorder"}, false, compositeKey{1, "old"}},
+		{compositeKey{3, ""}, compositeKey{3, "missing"}, false, compositeKey{1, "old"}},
+		{compositeKey{1, ""}, compositeKey{1, "new"}, true, compositeKey{1, "new"}},
+	}
+	for _, td := range testData {
+		if got := tree.UpdateKey(td.key, td.newKey); got != td.want {
+			t.Fatalf("tree.UpdateKey(%v, %v) = %v; want %v", td.key, td.newKey, got, td.want)
+		}
+		if k, _, _ := tree.FindLowest(); k != td.wantKey {
+			t.Fatalf("stored key = %v; want %v", k, td.wantKey)
+		}
+	}
+}
+
+// AddIfAbsent should not overwrite existing associations.
+func TestAddIfAbsent(t *testing.T) {
+	tree := newTree([]keyType{1})
+	if tree.AddIfAbsent(1, 10) {
+		t.Fatalf("tree.AddIfAbsent(1, 10) = true; want false")
+	}
+	if !tree.AddIfAbsent(2, 20) {
+		t.Fatalf("tree.AddIfAbsent(2, 20) = false; want true")
+	}
+	if got, want := fmt.Sprint(getIterSeq(tree.NewIterator())), "[{1 1} {2 20}]"; got != want {
+		t.Fatalf("got sequence %v; want %v", got, want)
+	}
+}
+
+// GetOrAdd should only add missing associations.
+func TestGetOrAdd(t *testing.T) {
+	tree := newTree([]keyType{1})
+	if v, found := tree.GetOrAdd(1, 10); v != 1 || !found {
+		t.Fatalf("tree.GetOrAdd(1, 10) = (%v, %v); want (%v, %v)", v, found, 1, true)
+	}
+	if v, found := tree.GetOrAdd(2, 20); v != 20 || found {
+		t.Fatalf("tree.GetOrAdd(2, 20) = (%v, %v); want (%v, %v)", v, found, 20, false)
+	}
+	if got, want := fmt.Sprint(getIterSeq(tree.NewIterator())), "[{1 1} {2 20}]"; got != want {
+		t.Fatalf("got sequence %v; want %v", got, want)
+	}
+}
+
+// Update should modify, insert or remove associations as decided by f.
+func TestUpdate(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 3})
+	f := func(old valType, found bool) (valType, bool) {
+		if !found {
+			return 40, true
+		}
+		return old * 10, old != 2
+	}
+	for _, k := range []keyType{1, 2, 4} {
+		tree.Update(k, f)
+	}
+	if got, want := fmt.Sprint(getIterSeq(tree.NewIterator())), "[{1 10} {3 3} {4 40}]"; got != want {
+		t.Fatalf("got sequence %v; want %v", got, want)
+	}
+
+	// Declining to insert a missing key should leave the tree unchanged.
+	tree.Update(5, func(valType, bool) (valType, bool) { return 50, false })
+	if got, want := tree.Length(), 3; got != want {
+		t.Fatalf("tree.Length() = %d; want %d", got, want)
+	}
+}
+
+// Update should add and remove associations in a single descent of the tree
+// while keeping it balanced.
+func TestUpdateSingleDescent(t *testing.T) {
+	compares := 0
+	tree := avltree.New(func(lhs, rhs keyType) int {
+		compares++
+		return math.CompareOrdered(lhs, rhs)
+	}, avltree.WithAppendOptimized[keyType, valType]())
+
+	rng := rand.New(rand.NewSource(1))
+	present := map[keyType]bool{}
+	for i := 0; i < 2000; i++ {
+		k := keyType(rng.Intn(200))
+		height := tree.Height()
+		compares = 0
+		tree.Update(k, func(old valType, found bool) (valType, bool) {
+			return valType(k), !found
+		})
+		if compares > height {
+			t.Fatalf("tree.Update(%d) compared %d keys in tree of height %d", k, compares, height)
+		}
+		present[k] = !present[k]
+		if balanced, sorted := tree.Validate(); !balanced || !sorted {
+			t.Fatalf("tree.Validate() = %v, %v; want true, true", balanced, sorted)
+		}
+	}
+
+	var want []keyType
+	for k := keyType(0); k < 200; k++ {
+		if present[k] {
+			want = append(want, k)
+		}
+	}
+	if got := getIterSeq(tree.NewIterator()); !checkIterSeq(got, want) {
+		t.Fatalf("unexpected sequence %v; want %v", got, want)
+	}
+
+	// The cached highest key used by WithAppendOptimized should be kept.
+	tree.Add(999, 999)
+	tree.Update(1001, func(valType, bool) (valType, bool) { return 1001, true })
+	tree.Add(1000, 1000)
+	want = append(want, 999, 1000, 1001)
+	if got := getIterSeq(tree.NewIterator()); !checkIterSeq(got, want) {
+		t.Fatalf("unexpected sequence %v; want %v", got, want)
+	}
+}
+
+// UpsertBatch should report outcomes in input order with the last of equal
+// keys stored.
+func TestUpsertBatch(t *testing.T) {
+	testData := []struct {
+		name    string
+		options []treeOptionType
+		want    string
+	}{
+		{"Unbounded", nil, "[{1 10} {2 2} {3 3} {5 50}]"},
+		{"MaxSize", []treeOptionType{avltree.WithMaxSize[keyType, valType](3, nil)}, "[{1 10} {3 3} {5 50}]"},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			tree := newTree([]keyType{2, 3}, td.options...)
+			outcomes := tree.UpsertBatch([]avltree.Assoc[keyType, valType]{
+				{Key: 5, Value: 5}, {Key: 3, Value: 3}, {Key: 1, Value: 10}, {Key: 5, Value: 50},
+			})
+			want := []avltree.Outcome{avltree.Inserted, avltree.Overwritten, avltree.Inserted, avltree.Overwritten}
+			if fmt.Sprint(outcomes) != fmt.Sprint(want) {
+				t.Fatalf("tree.UpsertBatch() = %v; want %v", outcomes, want)
+			}
+			if got := fmt.Sprint(getIterSeq(tree.NewIterator())); got != td.want {
+				t.Fatalf("got sequence %v; want %v", got, td.want)
+			}
+		})
+	}
+}
+
+// Increment should add to existing values and insert missing ones.
+func TestIncrement(t *testing.T) {
+	tree := newTree([]keyType{1, 2})
+	testData := []struct {
+		key   keyType
+		delta valType
+		want  valType
+	}{
+		{1, 10, 11},
+		{1, -2, 9},
+		{3, 5, 5},
+		{3, 1, 6},
+	}
+	for _, td := range testData {
+		if got := avltree.Increment(tree, td.key, td.delta); got != td.want {
+			t.Fatalf("avltree.Increment(tree, %d, %d) = %d; want %d", td.key, td.delta, got, td.want)
+		}
+		if v, _ := tree.Find(td.key); v != td.want {
+			t.Fatalf("tree.Find(%d) = %d; want %d", td.key, v, td.want)
+		}
+	}
+	if got, want := tree.Length(), 3; got != want {
+		t.Fatalf("tree.Length() = %d; want %d", got, want)
+	}
+}
+
 // Iterators should be updated by by insert and remove operations.
 func TestIteratorUpdate(t *testing.T) {
 	testData := []struct {
@@ -283,112 +636,1672 @@ func TestApply(t *testing.T) {
 	}
 }
 
-// Length should reflect the number of associations in a tree.
-func TestLength(t *testing.T) {
-	seq := []keyType{1, 2, 3}
-	tree := newTree(nil)
-	count := 0
+// ApplyContext should stop on function errors and context cancellation.
+func TestApplyContext(t *testing.T) {
+	var seq []keyType
+	for k := keyType(0); k < 200; k++ {
+		seq = append(seq, k)
+	}
+	tree := newTree(seq)
 
-	// Testing that length is updated by adding associations.
-	for _, k := range seq {
-		if n := tree.Length(); n != count {
-			t.Fatalf("tree.Len() = %d; want %d", n, count)
+	var visited []assoc
+	err := tree.ApplyContext(context.Background(), func(k keyType, v valType) error {
+		visited = append(visited, assoc{key: k, val: v})
+		return nil
+	})
+	if err != nil || !checkIterSeq(visited, seq) {
+		t.Fatalf("tree.ApplyContext() = %v visiting %v; want nil visiting %v", err, visited, seq)
+	}
+
+	errStop := errors.New("stop")
+	n := 0
+	err = tree.ApplyContext(context.Background(), func(k keyType, v valType) error {
+		if n++; n == 3 {
+			return errStop
 		}
-		tree.Add(k, valType(k))
-		count++
+		return nil
+	})
+	if err != errStop || n != 3 {
+		t.Fatalf("tree.ApplyContext() = %v after %d visits; want %v after 3 visits", err, n, errStop)
 	}
 
-	// Testing that length is updated by removing associations.
-	for _, k := range seq {
-		if n := tree.Length(); n != count {
-			t.Fatalf("tree.Len() = %d; want %d", n, count)
+	ctx, cancel := context.WithCancel(context.Background())
+	n = 0
+	err = tree.ApplyContext(ctx, func(k keyType, v valType) error {
+		if n++; n == 1 {
+			cancel()
 		}
-		tree.Remove(k)
-		count--
+		return nil
+	})
+	if err != context.Canceled || n >= len(seq) {
+		t.Fatalf("tree.ApplyContext() = %v after %d visits; want %v before all visits", err, n, context.Canceled)
 	}
+}
 
-	// Testing the length after clearing a tree is done by TestClear.
+// CollectSorted should project associations in ascending key order.
+func TestCollectSorted(t *testing.T) {
+	project := func(k keyType, v valType) string { return fmt.Sprintf("%v=%v", k, v) }
+
+	if got := avltree.CollectSorted(newTree(nil), project); got != nil {
+		t.Fatalf("avltree.CollectSorted(empty) = %#v; want nil", got)
+	}
+
+	got := avltree.CollectSorted(newTree([]keyType{3, 1, 2}), project)
+	if s, want := fmt.Sprint(got), "[1=1 2=2 3=3]"; s != want || cap(got) != 3 {
+		t.Fatalf("avltree.CollectSorted() = %s (cap %d); want %s (cap 3)", s, cap(got), want)
+	}
 }
 
-// Find should return expected results.
-func TestFind(t *testing.T) {
-	tree := newTree([]keyType{2, 5, 6, 7, 10})
+// ParallelApply should visit all associations exactly once.
+func TestParallelApply(t *testing.T) {
+	var seq []keyType
+	for k := keyType(0); k < 100; k++ {
+		seq = append(seq, k)
+	}
+	tree := newTree(seq)
+
+	for _, parts := range []int{-1, 1, 3, 7, 100, 200} {
+		t.Run(fmt.Sprint(parts), func(t *testing.T) {
+			var mu sync.Mutex
+			visited := make(map[keyType]valType)
+			tree.ParallelApply(parts, func(k keyType, v valType) {
+				mu.Lock()
+				defer mu.Unlock()
+				if _, ok := visited[k]; ok {
+					t.Errorf("key %v visited twice", k)
+				}
+				visited[k] = v
+			})
+			if len(visited) != len(seq) {
+				t.Fatalf("visited %d associations; want %d", len(visited), len(seq))
+			}
+			for k, v := range visited {
+				if valType(k) != v {
+					t.Fatalf("visited (%v, %v); want (%v, %v)", k, v, k, valType(k))
+				}
+			}
+		})
+	}
 
+	newTree(nil).ParallelApply(4, func(keyType, valType) {
+		t.Fatalf("unexpected visit of empty tree")
+	})
+}
+
+// ApplyLeaves should only visit leaf associations in order.
+func TestApplyLeaves(t *testing.T) {
 	testData := []struct {
-		name string
-		key  keyType
-		want string
+		keys []keyType
+		want []keyType
 	}{
-		{"Find(NonExisting)", 1, "0,false"},
-		{"Find(NonExisting)", 4, "0,false"},
-		{"Find(NonExisting)", 8, "0,false"},
-		{"Find(NonExisting)", 11, "0,false"},
-		{"Find(Existing)", 2, "2,true"},
-		{"Find(Existing)", 6, "6,true"},
-		{"Find(Existing)", 10, "10,true"},
+		{nil, []keyType{}},
+		{[]keyType{1}, []keyType{1}},
+		{[]keyType{1, 2, 3, 4, 5, 6, 7}, []keyType{1, 3, 5, 7}},
+		{[]keyType{2, 1, 3, 4}, []keyType{1, 4}}, // 2(1,3(,4))
+	}
+	for _, td := range testData {
+		var visited []assoc
+		newTree(td.keys).ApplyLeaves(func(k keyType, v valType) {
+			visited = append(visited, assoc{key: k, val: v})
+		})
+		if !checkIterSeq(visited, td.want) {
+			t.Fatalf("unexpected leaves %v of %v; want %v", visited, td.keys, td.want)
+		}
 	}
+}
 
-	for i, td := range testData {
-		t.Run(fmt.Sprintf("%s/%d", td.name, i), func(t *testing.T) {
-			if got, want := vResultString(tree.Find(td.key)), td.want; got != want {
-				t.Fatalf("tree.FindOp(%d) = %s; want %s", td.key, got, want)
+// ApplyLevelOrder should visit tree associations level by level.
+func TestApplyLevelOrder(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 3, 4, 5, 6, 7})
+
+	var visited []string
+	tree.ApplyLevelOrder(func(level int, k keyType, v valType) {
+		visited = append(visited, fmt.Sprintf("%d:%v,%v", level, k, v))
+	})
+
+	want := "[0:4,4 1:2,2 1:6,6 2:1,1 2:3,3 2:5,5 2:7,7]"
+	if got := fmt.Sprint(visited); got != want {
+		t.Fatalf("unexpected visited sequence %v; want %v", got, want)
+	}
+
+	newTree(nil).ApplyLevelOrder(func(int, keyType, valType) {
+		t.Fatalf("unexpected visit of empty tree")
+	})
+}
+
+// Split should partition the associations at a key and empty the tree.
+func TestSplit(t *testing.T) {
+	for _, td := range []struct {
+		name   string
+		option treeOptionType
+	}{
+		{"SyncPool", avltree.WithSyncPool[keyType, valType]()},
+		{"Arena", avltree.WithArena[keyType, valType]()},
+	} {
+		t.Run(td.name, func(t *testing.T) {
+			for key := keyType(0); key <= 10; key++ {
+				tree := newTree([]keyType{1, 2, 3, 4, 5, 6, 7, 8, 9}, td.option)
+				iter := tree.NewIterator()
+				left, right := tree.Split(key)
+
+				var wantLeft, wantRight []keyType
+				for k := keyType(1); k <= 9; k++ {
+					if k < key {
+						wantLeft = append(wantLeft, k)
+					} else {
+						wantRight = append(wantRight, k)
+					}
+				}
+				for _, part := range []struct {
+					tree *treeType
+					want []keyType
+				}{{left, wantLeft}, {right, wantRight}} {
+					if balanced, sorted := part.tree.Validate(); !balanced || !sorted {
+						t.Fatalf("Validate() = %v, %v; want true, true", balanced, sorted)
+					}
+					if got := getIterSeq(part.tree.NewIterator()); !checkIterSeq(got, part.want) {
+						t.Fatalf("tree.Split(%d) part sequence %v; want %v", key, got, part.want)
+					}
+				}
+				if got := tree.Length(); got != 0 {
+					t.Fatalf("tree.Length() after Split = %d; want 0", got)
+				}
+				if got, want := kvResultString(iter.Next()), kvResultString(0, 0, false); got != want {
+					t.Fatalf("iter.Next() after Split = %v; want %v", got, want)
+				}
+
+				// The trees must stay independent.
+				tree.Add(key, valType(key))
+				left.Clear(nil)
+				if got := getIterSeq(right.NewIterator()); !checkIterSeq(got, wantRight) {
+					t.Fatalf("right sequence after Clear of left %v; want %v", got, wantRight)
+				}
 			}
 		})
 	}
+}
 
-	testData2 := []struct {
-		name string
-		find func(keyType) (keyType, valType, bool)
-		key  keyType
-		want string
+// RemoveRange should remove and release the keys in the inclusive range.
+func TestRemoveRange(t *testing.T) {
+	testData := []struct {
+		lo, hi keyType
+		want   []keyType
 	}{
-		{"FindEqualOrLesser(NonExisting)", tree.FindEqualOrLesser, 11, "10,10,true"},
-		{"FindEqualOrLesser(NonExisting)", tree.FindEqualOrLesser, 9, "7,7,true"},
-		{"FindEqualOrLesser(NonExisting)", tree.FindEqualOrLesser, 4, "2,2,true"},
-		{"FindEqualOrLesser(NonExisting)", tree.FindEqualOrLesser, 1, "0,0,false"},
-		{"FindEqualOrLesser(Existing)", tree.FindEqualOrLesser, 2, "2,2,true"},
-		{"FindEqualOrLesser(Existing)", tree.FindEqualOrLesser, 6, "6,6,true"},
-		{"FindEqualOrLesser(Existing)", tree.FindEqualOrLesser, 10, "10,10,true"},
-		{"FindEqualOrGreater(NonExisting)", tree.FindEqualOrGreater, 11, "0,0,false"},
-		{"FindEqualOrGreater(NonExisting)", tree.FindEqualOrGreater, 8, "10,10,true"},
-		{"FindEqualOrGreater(NonExisting)", tree.FindEqualOrGreater, 3, "5,5,true"},
-		{"FindEqualOrGreater(NonExisting)", tree.FindEqualOrGreater, 1, "2,2,true"},
-		{"FindEqualOrGreater(Existing)", tree.FindEqualOrGreater, 2, "2,2,true"},
-		{"FindEqualOrGreater(Existing)", tree.FindEqualOrGreater, 6, "6,6,true"},
-		{"FindEqualOrGreater(Existing)", tree.FindEqualOrGreater, 10, "10,10,true"},
+		{0, 10, []keyType{}},
+		{3, 7, []keyType{1, 9}},
+		{2, 8, []keyType{1, 9}},
+		{5, 5, []keyType{1, 3, 7, 9}},
+		{4, 4, []keyType{1, 3, 5, 7, 9}},
+		{7, 3, []keyType{1, 3, 5, 7, 9}},
 	}
-
-	for i, td := range testData2 {
-		t.Run(fmt.Sprintf("%s/%d", td.name, i), func(t *testing.T) {
-			if got, want := kvResultString(td.find(td.key)), td.want; got != want {
-				t.Fatalf("tree.FindOp(%d) = %s; want %s", td.key, got, want)
-			}
+	for _, td := range testData {
+		tree := newTree([]keyType{1, 3, 5, 7, 9})
+		var released []keyType
+		n := tree.RemoveRange(td.lo, td.hi, func(k keyType, v valType) {
+			released = append(released, k)
 		})
+
+		if got := getIterSeq(tree.NewIterator()); !checkIterSeq(got, td.want) {
+			t.Fatalf("tree.RemoveRange(%d, %d) sequence %v; want %v", td.lo, td.hi, got, td.want)
+		}
+		if want := 5 - len(td.want); n != want || len(released) != want {
+			t.Fatalf("tree.RemoveRange(%d, %d) = %d, released %v; want %d", td.lo, td.hi, n, released, want)
+		}
+		if balanced, sorted := tree.Validate(); !balanced || !sorted {
+			t.Fatalf("tree.Validate() = %v, %v; want true, true", balanced, sorted)
+		}
+	}
+
+	// Iterators positioned in the range should move past it.
+	tree := newTree([]keyType{1, 3, 5, 7, 9})
+	iter := tree.NewIterator()
+	iter.Next()
+	tree.RemoveRange(2, 6, nil)
+	if want := []keyType{7, 9}; !checkIterSeq(getIterSeq(iter), want) {
+		t.Fatalf("iterator did not move past removed range; want %v", want)
 	}
 }
 
-// FindLowest should return the association with the lowest key.
-func TestFindLowest(t *testing.T) {
-	tree := newTree(nil)
-	if got, want := kvResultString(tree.FindLowest()), kvResultString(0, 0, false); got != want {
-		t.Fatalf("tree.FindLowest() = %v; want %v", got, want)
+// BuildSorted should build a valid tree from sorted input and reject unsorted
+// input.
+func TestBuildSorted(t *testing.T) {
+	for n := 0; n <= 33; n++ {
+		keys, values := make([]keyType, n), make([]valType, n)
+		for i := range keys {
+			keys[i], values[i] = keyType(i*2), valType(i*2)
+		}
+		tree := avltree.BuildSorted(math.CompareOrdered[keyType], keys, values)
+		if balanced, sorted := tree.Validate(); !balanced || !sorted {
+			t.Fatalf("n=%d: tree.Validate() = %v, %v; want true, true", n, balanced, sorted)
+		}
+		if got := getIterSeq(tree.NewIterator()); !checkIterSeq(got, keys) {
+			t.Fatalf("n=%d: got sequence %v; want %v", n, got, keys)
+		}
 	}
 
-	tree = newTree([]keyType{1, 2, 3, 4, 5})
-	if got, want := kvResultString(tree.FindLowest()), kvResultString(1, 1, true); got != want {
-		t.Fatalf("tree.FindLowest() = %v; want %v", got, want)
+	for _, keys := range [][]keyType{{1, 3, 2}, {1, 2, 2}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("avltree.BuildSorted(%v) did not panic", keys)
+				}
+			}()
+			avltree.BuildSorted(math.CompareOrdered[keyType], keys, make([]valType, len(keys)))
+		}()
 	}
 }
 
-func TestFindHighest(t *testing.T) {
-	tree := newTree(nil)
-	if got, want := kvResultString(tree.FindHighest()), kvResultString(0, 0, false); got != want {
-		t.Fatalf("tree.FindHighest() = %v; want %v", got, want)
+// SplitTopN should move the highest associations to a new valid tree.
+func TestSplitTopN(t *testing.T) {
+	seq := []keyType{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	testData := []struct {
+		n       int
+		wantLow []keyType
+		wantTop []keyType
+	}{
+		{-1, seq, []keyType{}},
+		{0, seq, []keyType{}},
+		{3, []keyType{1, 2, 3, 4, 5, 6}, []keyType{7, 8, 9}},
+		{8, []keyType{1}, []keyType{2, 3, 4, 5, 6, 7, 8, 9}},
+		{10, []keyType{}, seq},
 	}
 
-	tree = newTree([]keyType{1, 2, 3, 4, 5})
-	if got, want := kvResultString(tree.FindHighest()), kvResultString(5, 5, true); got != want {
-		t.Fatalf("tree.FindHighest() = %v; want %v", got, want)
+	for _, td := range testData {
+		t.Run(fmt.Sprint(td.n), func(t *testing.T) {
+			low := newTree(seq)
+			top := low.SplitTopN(td.n)
+
+			for _, tc := range []struct {
+				name string
+				tree *treeType
+				want []keyType
+			}{{"low", low, td.wantLow}, {"top", top, td.wantTop}} {
+				if balanced, sorted := tc.tree.Validate(); !balanced || !sorted {
+					t.Fatalf("Invalid %s tree invariant: balanced=%v, sorted=%v", tc.name, balanced, sorted)
+				}
+				if got, want := tc.tree.Length(), len(tc.want); got != want {
+					t.Fatalf("%s tree.Length() = %d; want %d", tc.name, got, want)
+				}
+				if got := getIterSeq(tc.tree.NewIterator()); !checkIterSeq(got, tc.want) {
+					t.Fatalf("unexpected %s sequence %v; want %v", tc.name, got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+// Frozen trees should panic on modification but allow reads.
+func TestFreeze(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 3})
+	tree.Freeze()
+
+	for _, td := range []struct {
+		name   string
+		modify func()
+	}{
+		{"Add", func() { tree.Add(4, 4) }},
+		{"Overwrite", func() { tree.Add(1, 1) }},
+		{"Remove", func() { tree.Remove(1) }},
+		{"Clear", func() { tree.Clear(nil) }},
+	} {
+		t.Run(td.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != "avltree: tree is frozen" {
+					t.Fatalf("recover() = %v; want frozen tree panic", r)
+				}
+			}()
+			td.modify()
+		})
+	}
+
+	if got := getIterSeq(tree.NewIterator()); !checkIterSeq(got, []keyType{1, 2, 3}) {
+		t.Fatalf("unexpected frozen tree sequence %v", got)
+	}
+
+	tree.Unfreeze()
+	tree.Add(4, 4)
+	if got := getIterSeq(tree.NewIterator()); !checkIterSeq(got, []keyType{1, 2, 3, 4}) {
+		t.Fatalf("unexpected unfrozen tree sequence %v", got)
+	}
+}
+
+// RotationCount should count rebalancing rotations.
+func TestRotationCount(t *testing.T) {
+	tree := newTree(nil)
+	testData := []struct {
+		modify func()
+		want   uint64
+	}{
+		{func() { bulkInsert(tree, []keyType{1, 2}) }, 0},
+		{func() { bulkInsert(tree, []keyType{3}) }, 1},    // Single rotation at 1 -> 2(1,3)
+		{func() { bulkInsert(tree, []keyType{5}) }, 1},    // 2(1,3(,5))
+		{func() { bulkInsert(tree, []keyType{4}) }, 2},    // Double rotation at 3 -> 2(1,4(3,5))
+		{func() { bulkRemove(tree, []keyType{1}) }, 3},    // Single rotation at 2 -> 4(2(,3),5)
+		{func() { bulkRemove(tree, []keyType{9, 5}) }, 4}, // Double rotation at 4 -> 3(2,4)
+	}
+	for i, td := range testData {
+		td.modify()
+		if got := tree.RotationCount(); got != td.want {
+			t.Fatalf("tree.RotationCount() = %d after step %d; want %d", got, i, td.want)
+		}
+	}
+}
+
+// Clones should be valid and independent of the original tree.
+func TestClone(t *testing.T) {
+	for _, td := range []struct {
+		name    string
+		options []treeOptionType
+	}{
+		{"Default", nil},
+		{"SyncPool", []treeOptionType{avltree.WithSyncPool[keyType, valType]()}},
+		{"Arena", []treeOptionType{avltree.WithArena[keyType, valType]()}},
+	} {
+		t.Run(td.name, func(t *testing.T) {
+			keys := []keyType{5, 3, 8, 1, 4, 7, 9, 2, 6}
+			tree := newTree(keys, td.options...)
+			clone := tree.Clone()
+			if balanced, sorted := clone.Validate(); !balanced || !sorted {
+				t.Fatalf("clone.Validate() = %v, %v; want true, true", balanced, sorted)
+			}
+
+			bulkRemove(tree, []keyType{1, 2})
+			clone.Add(10, 10)
+			clone.Add(5, 50)
+
+			want := "[{3 3} {4 4} {5 5} {6 6} {7 7} {8 8} {9 9}]"
+			if got := fmt.Sprint(getIterSeq(tree.NewIterator())); got != want {
+				t.Fatalf("tree sequence %v; want %v", got, want)
+			}
+			want = "[{1 1} {2 2} {3 3} {4 4} {5 50} {6 6} {7 7} {8 8} {9 9} {10 10}]"
+			if got := fmt.Sprint(getIterSeq(clone.NewIterator())); got != want {
+				t.Fatalf("clone sequence %v; want %v", got, want)
+			}
+		})
+	}
+}
+
+// RemoveAndGet should return the value of the removed association.
+func TestRemoveAndGet(t *testing.T) {
+	var released []keyType
+	tree := newTree([]keyType{1, 2, 3}, avltree.WithSyncPool[keyType, valType]())
+	for _, k := range []keyType{2, 4, 2} {
+		v, ok := tree.RemoveAndGet(k)
+		if ok {
+			released = append(released, keyType(v))
+		}
+		if _, found := tree.Find(k); found {
+			t.Fatalf("tree.Find(%d) found association after tree.RemoveAndGet(%d)", k, k)
+		}
+	}
+	if got, want := fmt.Sprint(released), "[2]"; got != want {
+		t.Fatalf("tree.RemoveAndGet(...) values %v; want %v", got, want)
+	}
+	if got, want := getIterSeq(tree.NewIterator()), []keyType{1, 3}; !checkIterSeq(got, want) {
+		t.Fatalf("unexpected sequence %v; want %v", got, want)
+	}
+}
+
+// Popping should remove and return the associations at the edges of the tree.
+func TestPopLowestHighest(t *testing.T) {
+	tree := newTree([]keyType{4, 2, 6, 1, 3, 5, 7})
+	iter := tree.NewIterator()
+
+	var got []string
+	for i := 0; tree.Length() > 0; i++ {
+		if i%2 == 0 {
+			got = append(got, kvResultString(tree.PopLowest()))
+		} else {
+			got = append(got, kvResultString(tree.PopHighest()))
+		}
+		if balanced, sorted := tree.Validate(); !balanced || !sorted {
+			t.Fatalf("tree.Validate() = %v, %v; want true, true", balanced, sorted)
+		}
+	}
+	want := "[1,1,true 7,7,true 2,2,true 6,6,true 3,3,true 5,5,true 4,4,true]"
+	if fmt.Sprint(got) != want {
+		t.Fatalf("popped %v; want %v", got, want)
+	}
+
+	// Iterators are updated like for Remove and fall off once the tree is empty.
+	if got, want := kvResultString(iter.Next()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("iter.Next() = %v; want %v", got, want)
+	}
+	if got, want := kvResultString(tree.PopLowest()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("empty tree.PopLowest() = %v; want %v", got, want)
+	}
+	if got, want := kvResultString(tree.PopHighest()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("empty tree.PopHighest() = %v; want %v", got, want)
+	}
+}
+
+// RemapKeys should build a new valid tree with remapped keys.
+func TestRemapKeys(t *testing.T) {
+	seq := []keyType{1, 2, 3, 4, 5}
+	tree := newTree(seq)
+
+	// Reverse the order and shift keys
+	remapped := tree.RemapKeys(
+		func(lhs, rhs keyType) int { return math.CompareOrdered(rhs, lhs) },
+		func(k keyType) keyType { return k + 10 },
+	)
+	if balanced, sorted := remapped.Validate(); !balanced || !sorted {
+		t.Fatalf("Invalid tree invariant: balanced=%v, sorted=%v", balanced, sorted)
+	}
+	want := "[{15 5} {14 4} {13 3} {12 2} {11 1}]"
+	if got := fmt.Sprint(getIterSeq(remapped.NewIterator())); got != want {
+		t.Fatalf("got remapped sequence %v; want %v", got, want)
+	}
+	if got := getIterSeq(tree.NewIterator()); !checkIterSeq(got, seq) {
+		t.Fatalf("got source sequence %v; want %v", got, seq)
+	}
+
+	// Colliding keys
+	collided := tree.RemapKeys(math.CompareOrdered[keyType], func(k keyType) keyType { return k / 2 })
+	want = "[{0 1} {1 3} {2 5}]"
+	if got := fmt.Sprint(getIterSeq(collided.NewIterator())); got != want {
+		t.Fatalf("got collided sequence %v; want %v", got, want)
+	}
+}
+
+// Length should reflect the number of associations in a tree.
+func TestLength(t *testing.T) {
+	seq := []keyType{1, 2, 3}
+	tree := newTree(nil)
+	count := 0
+
+	// Testing that length is updated by adding associations.
+	for _, k := range seq {
+		if n := tree.Length(); n != count {
+			t.Fatalf("tree.Len() = %d; want %d", n, count)
+		}
+		tree.Add(k, valType(k))
+		count++
+	}
+
+	// Testing that length is updated by removing associations.
+	for _, k := range seq {
+		if n := tree.Length(); n != count {
+			t.Fatalf("tree.Len() = %d; want %d", n, count)
+		}
+		tree.Remove(k)
+		count--
+	}
+
+	// Testing the length after clearing a tree is done by TestClear.
+}
+
+// Find should return expected results.
+func TestFind(t *testing.T) {
+	tree := newTree([]keyType{2, 5, 6, 7, 10})
+
+	testData := []struct {
+		name string
+		key  keyType
+		want string
+	}{
+		{"Find(NonExisting)", 1, "0,false"},
+		{"Find(NonExisting)", 4, "0,false"},
+		{"Find(NonExisting)", 8, "0,false"},
+		{"Find(NonExisting)", 11, "0,false"},
+		{"Find(Existing)", 2, "2,true"},
+		{"Find(Existing)", 6, "6,true"},
+		{"Find(Existing)", 10, "10,true"},
+	}
+
+	for i, td := range testData {
+		t.Run(fmt.Sprintf("%s/%d", td.name, i), func(t *testing.T) {
+			if got, want := vResultString(tree.Find(td.key)), td.want; got != want {
+				t.Fatalf("tree.FindOp(%d) = %s; want %s", td.key, got, want)
+			}
+		})
+	}
+
+	testData2 := []struct {
+		name string
+		find func(keyType) (keyType, valType, bool)
+		key  keyType
+		want string
+	}{
+		{"FindEqualOrLesser(NonExisting)", tree.FindEqualOrLesser, 11, "10,10,true"},
+		{"FindEqualOrLesser(NonExisting)", tree.FindEqualOrLesser, 9, "7,7,true"},
+		{"FindEqualOrLesser(NonExisting)", tree.FindEqualOrLesser, 4, "2,2,true"},
+		{"FindEqualOrLesser(NonExisting)", tree.FindEqualOrLesser, 1, "0,0,false"},
+		{"FindEqualOrLesser(Existing)", tree.FindEqualOrLesser, 2, "2,2,true"},
+		{"FindEqualOrLesser(Existing)", tree.FindEqualOrLesser, 6, "6,6,true"},
+		{"FindEqualOrLesser(Existing)", tree.FindEqualOrLesser, 10, "10,10,true"},
+		{"FindEqualOrGreater(NonExisting)", tree.FindEqualOrGreater, 11, "0,0,false"},
+		{"FindEqualOrGreater(NonExisting)", tree.FindEqualOrGreater, 8, "10,10,true"},
+		{"FindEqualOrGreater(NonExisting)", tree.FindEqualOrGreater, 3, "5,5,true"},
+		{"FindEqualOrGreater(NonExisting)", tree.FindEqualOrGreater, 1, "2,2,true"},
+		{"FindEqualOrGreater(Existing)", tree.FindEqualOrGreater, 2, "2,2,true"},
+		{"FindEqualOrGreater(Existing)", tree.FindEqualOrGreater, 6, "6,6,true"},
+		{"FindEqualOrGreater(Existing)", tree.FindEqualOrGreater, 10, "10,10,true"},
+		{"FloorInclusive(NonExisting)", tree.FloorInclusive, 1, "0,0,false"},
+		{"FloorInclusive(NonExisting)", tree.FloorInclusive, 9, "7,7,true"},
+		{"FloorInclusive(Existing)", tree.FloorInclusive, 2, "2,2,true"},
+		{"FloorExclusive(NonExisting)", tree.FloorExclusive, 1, "0,0,false"},
+		{"FloorExclusive(NonExisting)", tree.FloorExclusive, 11, "10,10,true"},
+		{"FloorExclusive(Existing)", tree.FloorExclusive, 2, "0,0,false"},
+		{"FloorExclusive(Existing)", tree.FloorExclusive, 5, "2,2,true"},
+		{"FloorExclusive(Existing)", tree.FloorExclusive, 6, "5,5,true"},
+		{"CeilInclusive(NonExisting)", tree.CeilInclusive, 11, "0,0,false"},
+		{"CeilInclusive(NonExisting)", tree.CeilInclusive, 3, "5,5,true"},
+		{"CeilInclusive(Existing)", tree.CeilInclusive, 10, "10,10,true"},
+		{"CeilExclusive(NonExisting)", tree.CeilExclusive, 11, "0,0,false"},
+		{"CeilExclusive(NonExisting)", tree.CeilExclusive, 1, "2,2,true"},
+		{"CeilExclusive(Existing)", tree.CeilExclusive, 10, "0,0,false"},
+		{"CeilExclusive(Existing)", tree.CeilExclusive, 7, "10,10,true"},
+		{"CeilExclusive(Existing)", tree.CeilExclusive, 6, "7,7,true"},
+		{"ClampKey(Below)", tree.ClampKey, 1, "2,2,true"},
+		{"ClampKey(Above)", tree.ClampKey, 11, "10,10,true"},
+		{"ClampKey(NonExisting)", tree.ClampKey, 8, "10,10,true"},
+		{"ClampKey(Existing)", tree.ClampKey, 6, "6,6,true"},
+		{"ClampKey(Empty)", newTree(nil).ClampKey, 6, "0,0,false"},
+	}
+
+	for i, td := range testData2 {
+		t.Run(fmt.Sprintf("%s/%d", td.name, i), func(t *testing.T) {
+			if got, want := kvResultString(td.find(td.key)), td.want; got != want {
+				t.Fatalf("tree.FindOp(%d) = %s; want %s", td.key, got, want)
+			}
+		})
+	}
+}
+
+// FindOr should return the found value or the fallback value.
+func TestFindOr(t *testing.T) {
+	tree := newTree([]keyType{0, 2})
+	testData := []struct {
+		key  keyType
+		want valType
+	}{
+		{0, 0}, // Stored zero value must not be mistaken for a missing key.
+		{1, 99},
+		{2, 2},
+	}
+	for _, td := range testData {
+		if got := tree.FindOr(td.key, 99); got != td.want {
+			t.Fatalf("tree.FindOr(%d, 99) = %v; want %v", td.key, got, td.want)
+		}
+	}
+}
+
+// FindWithNeighbors should agree with Find, FloorExclusive and CeilExclusive.
+func TestFindWithNeighbors(t *testing.T) {
+	tree := newTree([]keyType{2, 4, 6, 8, 10, 12, 14})
+	for key := keyType(0); key <= 16; key++ {
+		v, found, prev, prevOK, next, nextOK := tree.FindWithNeighbors(key)
+		got := fmt.Sprintf("%v,%v %v,%v %v,%v", v, found, prev, prevOK, next, nextOK)
+
+		wantV, wantFound := tree.Find(key)
+		wantPrev, _, wantPrevOK := tree.FloorExclusive(key)
+		wantNext, _, wantNextOK := tree.CeilExclusive(key)
+		want := fmt.Sprintf("%v,%v %v,%v %v,%v", wantV, wantFound, wantPrev, wantPrevOK, wantNext, wantNextOK)
+
+		if got != want {
+			t.Fatalf("tree.FindWithNeighbors(%d) = %v; want %v", key, got, want)
+		}
+	}
+}
+
+// Contains should report membership of keys.
+func TestContains(t *testing.T) {
+	tree := newTree([]keyType{1, 3})
+	for _, td := range []struct {
+		key  keyType
+		want bool
+	}{
+		{0, false}, {1, true}, {2, false}, {3, true}, {4, false},
+	} {
+		if got := tree.Contains(td.key); got != td.want {
+			t.Fatalf("tree.Contains(%d) = %v; want %v", td.key, got, td.want)
+		}
+	}
+}
+
+// ContainsAll and ContainsAny should test membership of many keys.
+func TestContainsAllAny(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 3})
+	testData := []struct {
+		keys    []keyType
+		wantAll bool
+		wantAny bool
+	}{
+		{nil, true, false},
+		{[]keyType{1, 3}, true, true},
+		{[]keyType{1, 4}, false, true},
+		{[]keyType{0, 4}, false, false},
+	}
+	for _, td := range testData {
+		if got := tree.ContainsAll(td.keys); got != td.wantAll {
+			t.Fatalf("tree.ContainsAll(%v) = %v; want %v", td.keys, got, td.wantAll)
+		}
+		if got := tree.ContainsAny(td.keys); got != td.wantAny {
+			t.Fatalf("tree.ContainsAny(%v) = %v; want %v", td.keys, got, td.wantAny)
+		}
+	}
+}
+
+// FindLowest should return the association with the lowest key.
+func TestFindLowest(t *testing.T) {
+	tree := newTree(nil)
+	if got, want := kvResultString(tree.FindLowest()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("tree.FindLowest() = %v; want %v", got, want)
+	}
+
+	tree = newTree([]keyType{1, 2, 3, 4, 5})
+	if got, want := kvResultString(tree.FindLowest()), kvResultString(1, 1, true); got != want {
+		t.Fatalf("tree.FindLowest() = %v; want %v", got, want)
+	}
+}
+
+func TestFindHighest(t *testing.T) {
+	tree := newTree(nil)
+	if got, want := kvResultString(tree.FindHighest()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("tree.FindHighest() = %v; want %v", got, want)
+	}
+
+	tree = newTree([]keyType{1, 2, 3, 4, 5})
+	if got, want := kvResultString(tree.FindHighest()), kvResultString(5, 5, true); got != want {
+		t.Fatalf("tree.FindHighest() = %v; want %v", got, want)
+	}
+}
+
+// String and Dump should format the associations and structure of the tree.
+func TestStringDump(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 3, 4})
+	if got, want := tree.String(), "{1:1 2:2 3:3 4:4}"; got != want {
+		t.Fatalf("tree.String() = %q; want %q", got, want)
+	}
+	if got, want := newTree(nil).String(), "{}"; got != want {
+		t.Fatalf("empty.String() = %q; want %q", got, want)
+	}
+
+	var b strings.Builder
+	if err := tree.Dump(&b); err != nil {
+		t.Fatalf("tree.Dump() = %v", err)
+	}
+	want := "        4:4 [0]\n" +
+		"    3:3 [1]\n" +
+		"2:2 [1]\n" +
+		"    1:1 [0]\n"
+	if got := b.String(); got != want {
+		t.Fatalf("tree.Dump() wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+// MostImbalancedKey should return the key with the greatest subtree height
+// difference.
+func TestMostImbalancedKey(t *testing.T) {
+	testData := []struct {
+		keys []keyType
+		want string
+	}{
+		{nil, "0,false"},
+		{[]keyType{1, 2, 3, 4, 5, 6, 7}, "1,true"}, // Perfectly balanced, lowest key wins
+		{[]keyType{2, 1, 3, 4}, "2,true"},          // 2(1,3(,4))
+		{[]keyType{2, 1, 4, 3, 5, 6}, "5,true"},    // 4(2(1,3),5(,6))
+	}
+	for _, td := range testData {
+		k, ok := newTree(td.keys).MostImbalancedKey()
+		if got := fmt.Sprintf("%v,%v", k, ok); got != td.want {
+			t.Fatalf("tree.MostImbalancedKey() of %v = %s; want %s", td.keys, got, td.want)
+		}
+	}
+}
+
+// AtFraction should return the association at a fractional position.
+func TestAtFraction(t *testing.T) {
+	tree := newTree([]keyType{10, 20, 30, 40, 50})
+	testData := []struct {
+		f    float64
+		want string
+	}{
+		{-1, "10,10,true"},
+		{0, "10,10,true"},
+		{0.3, "20,20,true"},
+		{0.4, "30,30,true"},
+		{0.5, "30,30,true"},
+		{1, "50,50,true"},
+		{2, "50,50,true"},
+	}
+	for _, td := range testData {
+		if got := kvResultString(tree.AtFraction(td.f)); got != td.want {
+			t.Fatalf("tree.AtFraction(%v) = %s; want %s", td.f, got, td.want)
+		}
+	}
+	if got, want := kvResultString(newTree(nil).AtFraction(0.5)), "0,0,false"; got != want {
+		t.Fatalf("tree.AtFraction(0.5) = %s; want %s", got, want)
+	}
+}
+
+// Median should return the lower median association.
+func TestMedian(t *testing.T) {
+	testData := []struct {
+		keys []keyType
+		want string
+	}{
+		{nil, "0,0,false"},
+		{[]keyType{1}, "1,1,true"},
+		{[]keyType{1, 2}, "1,1,true"},
+		{[]keyType{1, 2, 3}, "2,2,true"},
+		{[]keyType{1, 2, 3, 4, 5, 6, 7, 8}, "4,4,true"},
+		{[]keyType{1, 2, 3, 4, 5, 6, 7, 8, 9}, "5,5,true"},
+	}
+	for _, td := range testData {
+		if got := kvResultString(newTree(td.keys).Median()); got != td.want {
+			t.Fatalf("tree.Median() of %v = %s; want %s", td.keys, got, td.want)
+		}
+	}
+}
+
+// Select should agree with the iteration order after insertions and removals.
+func TestSelect(t *testing.T) {
+	tree := newTree(nil)
+	for i := 0; i < 200; i++ {
+		tree.Add(keyType((i*37)%101), valType((i*37)%101))
+		if i%3 == 0 {
+			tree.Remove(keyType((i * 11) % 101))
+		}
+	}
+	tree.MergeSorted([]avltree.Assoc[keyType, valType]{{Key: 150, Value: 150}, {Key: 160, Value: 160}}, nil)
+	if balanced, sorted := tree.Validate(); !balanced || !sorted {
+		t.Fatalf("tree.Validate() = %v, %v; want true, true", balanced, sorted)
+	}
+
+	seq := getIterSeq(tree.NewIterator())
+	for rank, a := range seq {
+		if got, want := kvResultString(tree.Select(rank)), kvResultString(a.key, a.val, true); got != want {
+			t.Fatalf("tree.Select(%d) = %v; want %v", rank, got, want)
+		}
+	}
+	for _, rank := range []int{-1, len(seq)} {
+		if got, want := kvResultString(tree.Select(rank)), kvResultString(0, 0, false); got != want {
+			t.Fatalf("tree.Select(%d) = %v; want %v", rank, got, want)
+		}
+	}
+}
+
+// Rank should count the keys less than key whether or not key exists.
+func TestRank(t *testing.T) {
+	keys := []keyType{2, 4, 6, 8, 10, 12, 14}
+	tree := newTree(keys)
+	for key := keyType(0); key <= 16; key++ {
+		rank, ok := tree.Rank(key)
+		wantRank, wantOK := 0, false
+		for _, k := range keys {
+			if k < key {
+				wantRank++
+			}
+			wantOK = wantOK || k == key
+		}
+		if rank != wantRank || ok != wantOK {
+			t.Fatalf("tree.Rank(%d) = (%d, %v); want (%d, %v)", key, rank, ok, wantRank, wantOK)
+		}
+	}
+}
+
+// Height should count the nodes on the longest path from the root.
+func TestHeight(t *testing.T) {
+	tree := newTree(nil)
+	if got := tree.Height(); got != 0 {
+		t.Fatalf("tree.Height() = %d; want 0", got)
+	}
+	for k := keyType(1); k <= 100; k++ {
+		tree.Add(k, valType(k))
+		levels := 0
+		tree.ApplyLevelOrder(func(level int, _ keyType, _ valType) {
+			levels = math.MaxInteger(levels, level+1)
+		})
+		if got := tree.Height(); got != levels {
+			t.Fatalf("tree.Height() = %d; want %d", got, levels)
+		}
+	}
+}
+
+// CountRange should count the keys in the inclusive range.
+func TestCountRange(t *testing.T) {
+	tree := newTree([]keyType{1, 3, 5, 7, 9})
+	testData := []struct {
+		lo, hi keyType
+		want   int
+	}{
+		{0, 10, 5},
+		{3, 7, 3},
+		{2, 8, 3},
+		{5, 5, 1},
+		{4, 4, 0},
+		{7, 3, 0},
+		{10, 20, 0},
+	}
+	for _, td := range testData {
+		if got := tree.CountRange(td.lo, td.hi); got != td.want {
+			t.Fatalf("tree.CountRange(%d, %d) = %d; want %d", td.lo, td.hi, got, td.want)
+		}
+	}
+}
+
+// Stats and SizeLeftOf should describe the shape of the tree.
+func TestStats(t *testing.T) {
+	// Inserting 1..6 in order produces:
+	//       4
+	//     2   5
+	//    1 3    6
+	tree := newTree([]keyType{1, 2, 3, 4, 5, 6})
+	want := avltree.TreeStats{Length: 6, Height: 3, Leaves: 3, MinLeafDepth: 2, MaxLeafDepth: 2}
+	if got := tree.Stats(); got != want {
+		t.Fatalf("tree.Stats() = %+v; want %+v", got, want)
+	}
+	tree.Remove(6)
+	want = avltree.TreeStats{Length: 5, Height: 3, Leaves: 3, MinLeafDepth: 1, MaxLeafDepth: 2}
+	if got := tree.Stats(); got != want {
+		t.Fatalf("tree.Stats() = %+v; want %+v", got, want)
+	}
+	if got := newTree(nil).Stats(); got != (avltree.TreeStats{}) {
+		t.Fatalf("empty tree.Stats() = %+v; want zero value", got)
+	}
+
+	for _, td := range []struct {
+		key  keyType
+		want string
+	}{
+		{4, "3 true"}, {2, "1 true"}, {5, "0 true"}, {6, "0 false"},
+	} {
+		n, ok := tree.SizeLeftOf(td.key)
+		if got := fmt.Sprint(n, ok); got != td.want {
+			t.Fatalf("tree.SizeLeftOf(%d) = %v; want %v", td.key, got, td.want)
+		}
+	}
+}
+
+// Depth should report the number of edges from the root to a key.
+func TestDepth(t *testing.T) {
+	// Inserting 1..7 in order produces a perfectly balanced tree rooted at 4.
+	tree := newTree([]keyType{1, 2, 3, 4, 5, 6, 7})
+
+	testData := []struct {
+		key  keyType
+		want string
+	}{
+		{4, "0,true"},
+		{2, "1,true"},
+		{6, "1,true"},
+		{1, "2,true"},
+		{7, "2,true"},
+		{8, "0,false"},
+	}
+	for _, td := range testData {
+		depth, ok := tree.Depth(td.key)
+		if got := fmt.Sprintf("%v,%v", depth, ok); got != td.want {
+			t.Fatalf("tree.Depth(%d) = %s; want %s", td.key, got, td.want)
+		}
+	}
+}
+
+// Trees with a maximum size should evict associations to make room for new ones.
+func TestMaxSize(t *testing.T) {
+	var tree *treeType
+	evictHighest := func(keyType, valType) (keyType, bool) {
+		k, _, ok := tree.FindHighest()
+		return k, ok
+	}
+
+	testData := []struct {
+		name  string
+		evict func(keyType, valType) (keyType, bool)
+		want  []keyType
+	}{
+		{"Default", nil, []keyType{3, 4, 5}},
+		{"Highest", evictHighest, []keyType{1, 2, 5}},
+		{"Declined", func(keyType, valType) (keyType, bool) { return 0, false }, []keyType{3, 4, 5}},
+		{"Missing", func(keyType, valType) (keyType, bool) { return 9, true }, []keyType{3, 4, 5}},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			tree = newTree([]keyType{1, 2, 3}, avltree.WithMaxSize(3, td.evict))
+
+			// Overwriting should not evict anything.
+			tree.Add(2, 2)
+			if got, want := tree.Length(), 3; got != want {
+				t.Fatalf("tree.Length() = %d; want %d", got, want)
+			}
+
+			bulkInsert(tree, []keyType{4, 5})
+			got := getIterSeq(tree.NewIterator())
+			if !checkIterSeq(got, td.want) {
+				t.Fatalf("got sequence %v; want %v", got, td.want)
+			}
+		})
+	}
+}
+
+// The least recently added or overwritten association should be evicted.
+func TestMaxSizeFIFO(t *testing.T) {
+	var evicted []assoc
+	onEvict := func(k keyType, v valType) { evicted = append(evicted, assoc{k, v}) }
+	tree := newTree([]keyType{5, 1, 3}, avltree.WithMaxSizeFIFO(3, onEvict))
+
+	tree.Add(5, 50)
+	bulkInsert(tree, []keyType{2, 4})
+
+	if got, want := fmt.Sprint(evicted), "[{1 1} {3 3}]"; got != want {
+		t.Fatalf("evicted %v; want %v", got, want)
+	}
+	if got, want := fmt.Sprint(getIterSeq(tree.NewIterator())), "[{2 2} {4 4} {5 50}]"; got != want {
+		t.Fatalf("got sequence %v; want %v", got, want)
+	}
+}
+
+// Keys should be canonicalized by the key interner.
+func TestKeyInterner(t *testing.T) {
+	interned := map[string]*string{}
+	intern := func(k *string) *string {
+		if p, ok := interned[*k]; ok {
+			return p
+		}
+		interned[*k] = k
+		return k
+	}
+	compare := func(lhs, rhs *string) int { return math.CompareOrdered(*lhs, *rhs) }
+	tree := avltree.New(compare, avltree.WithKeyInterner[*string, int](intern))
+
+	key1, key2 := "key", "key"
+	tree.Add(&key1, 1)
+	tree.Add(&key2, 2)
+
+	k, v, _ := tree.FindLowest()
+	if k != &key1 || v != 2 {
+		t.Fatalf("tree.FindLowest() = (%p, %v); want (%p, %v)", k, v, &key1, 2)
+	}
+}
+
+// Add should compare keys at most once per node on the search path.
+func TestAddComparisons(t *testing.T) {
+	var compares int
+	compare := func(lhs, rhs keyType) int {
+		compares++
+		return math.CompareOrdered(lhs, rhs)
+	}
+	tree := avltree.New[keyType, valType](compare)
+
+	for i := 0; i < 1000; i++ {
+		key := keyType((i * 7919) % 1000)
+		levels := 0
+		tree.ApplyLevelOrder(func(level int, _ keyType, _ valType) {
+			levels = math.MaxInteger(levels, level+1)
+		})
+
+		compares = 0
+		tree.Add(key, valType(key))
+		if compares > levels {
+			t.Fatalf("tree.Add(%d) compared keys %d times; want at most %d", key, compares, levels)
+		}
+	}
+	if balanced, sorted := tree.Validate(); !balanced || !sorted {
+		t.Fatalf("tree.Validate() = %v, %v; want true, true", balanced, sorted)
+	}
+}
+
+// Appending keys should take a single comparison per key and fall back to a
+// normal insert for keys that are not the highest.
+func TestAppendOptimized(t *testing.T) {
+	var compares int
+	compare := func(lhs, rhs keyType) int {
+		compares++
+		return math.CompareOrdered(lhs, rhs)
+	}
+	tree := avltree.New(compare, avltree.WithAppendOptimized[keyType, valType]())
+
+	tree.Add(0, 0)
+	compares = 0
+	for k := keyType(1); k < 1000; k++ {
+		tree.Add(k, valType(k))
+	}
+	if want := 999; compares != want {
+		t.Fatalf("compares = %d; want %d", compares, want)
+	}
+	if balanced, sorted := tree.Validate(); !balanced || !sorted {
+		t.Fatalf("tree.Validate() = %v, %v; want true, true", balanced, sorted)
+	}
+
+	// Mix in non-maximal keys and removal of the highest key.
+	tree = avltree.New(compare, avltree.WithAppendOptimized[keyType, valType]())
+	var want []keyType
+	for k := keyType(0); k < 100; k++ {
+		tree.Add(k*2+1, valType(k*2+1))
+		if k%3 == 0 {
+			tree.Add(k*2, valType(k*2))
+		}
+		if k%5 == 0 {
+			tree.Remove(k*2 + 1)
+		}
+		if balanced, sorted := tree.Validate(); !balanced || !sorted {
+			t.Fatalf("tree.Validate() = %v, %v; want true, true", balanced, sorted)
+		}
+	}
+	for k := keyType(0); k < 200; k++ {
+		if (k%2 == 1 && (k/2)%5 != 0) || (k%2 == 0 && (k/2)%3 == 0) {
+			want = append(want, k)
+		}
+	}
+	if got := getIterSeq(tree.NewIterator()); !checkIterSeq(got, want) {
+		t.Fatalf("got sequence %v; want %v", got, want)
+	}
+}
+
+// Pool statistics should account for nodes in use and returned to the pool.
+func TestPoolStats(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 3})
+	if inUse, pooled := tree.PoolStats(); inUse != 3 || pooled != 0 {
+		t.Fatalf("tree.PoolStats() = (%d, %d); want (%d, %d)", inUse, pooled, 3, 0)
+	}
+
+	tree = newTree([]keyType{1, 2, 3}, avltree.WithSyncPool[keyType, valType]())
+	bulkRemove(tree, []keyType{1, 2})
+	if inUse, pooled := tree.PoolStats(); inUse != 1 || pooled != 2 {
+		t.Fatalf("tree.PoolStats() = (%d, %d); want (%d, %d)", inUse, pooled, 1, 2)
+	}
+}
+
+// Nodes kept for reuse should not retain pointers after Remove and Clear.
+func TestPooledNodesCleared(t *testing.T) {
+	for _, td := range []struct {
+		name   string
+		option avltree.TreeOption[string, *int]
+	}{
+		{"SyncPool", avltree.WithSyncPool[string, *int]()},
+		{"Arena", avltree.WithArena[string, *int]()},
+	} {
+		t.Run(td.name, func(t *testing.T) {
+			tree := avltree.New(math.CompareOrdered[string], td.option)
+			for i := 0; i < 100; i++ {
+				v := i
+				tree.Add(fmt.Sprint(i), &v)
+			}
+			for i := 0; i < 100; i += 2 {
+				tree.Remove(fmt.Sprint(i))
+			}
+			inUse, pooled := tree.PoolStats()
+			if err := tree.AssertPooledNodesCleared(); err != nil {
+				t.Fatalf("after Remove: tree.AssertPooledNodesCleared() = %v; want nil", err)
+			}
+			if gotInUse, gotPooled := tree.PoolStats(); gotInUse != inUse || gotPooled != pooled {
+				t.Fatalf("tree.PoolStats() = (%d, %d) after check; want (%d, %d)", gotInUse, gotPooled, inUse, pooled)
+			}
+			tree.Clear(nil)
+			if err := tree.AssertPooledNodesCleared(); err != nil {
+				t.Fatalf("after Clear: tree.AssertPooledNodesCleared() = %v; want nil", err)
+			}
+		})
+	}
+}
+
+// ApproxPercentile should select the sampled key nearest the percentile.
+func TestApproxPercentile(t *testing.T) {
+	var seq []keyType
+	for k := keyType(0); k < 100; k++ {
+		seq = append(seq, k)
+	}
+	tree := newTree(seq)
+	zero := 0.0
+
+	testData := []struct {
+		p           float64
+		sampleEvery int
+		want        string
+	}{
+		{0, 10, "0,true"},
+		{-1, 10, "0,true"},
+		{zero / zero, 10, "0,true"}, // NaN
+		{0.5, 10, "50,true"},
+		{0.42, 10, "40,true"},
+		{1, 10, "90,true"},
+		{2, 1, "99,true"},
+		{0.25, 1, "25,true"},
+		{0.25, 0, "25,true"},
+	}
+	for _, td := range testData {
+		k, ok := tree.ApproxPercentile(td.p, td.sampleEvery)
+		if got := fmt.Sprintf("%v,%v", k, ok); got != td.want {
+			t.Fatalf("tree.ApproxPercentile(%v, %d) = %s; want %s", td.p, td.sampleEvery, got, td.want)
+		}
+	}
+
+	if k, ok := newTree(nil).ApproxPercentile(0.5, 1); ok {
+		t.Fatalf("tree.ApproxPercentile() = %v,%v on empty tree; want 0,false", k, ok)
+	}
+}
+
+// Arena allocated trees should reuse removed nodes and reclaim all on Clear.
+func TestArena(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 3, 4}, avltree.WithArena[keyType, valType]())
+	bulkRemove(tree, []keyType{1, 3})
+	if inUse, pooled := tree.PoolStats(); inUse != 2 || pooled != 2 {
+		t.Fatalf("tree.PoolStats() = (%d, %d); want (%d, %d)", inUse, pooled, 2, 2)
+	}
+
+	bulkInsert(tree, []keyType{5})
+	if inUse, pooled := tree.PoolStats(); inUse != 3 || pooled != 1 {
+		t.Fatalf("tree.PoolStats() = (%d, %d); want (%d, %d)", inUse, pooled, 3, 1)
+	}
+
+	tree.Clear(nil)
+	if inUse, pooled := tree.PoolStats(); inUse != 0 || pooled != 0 {
+		t.Fatalf("tree.PoolStats() = (%d, %d); want (%d, %d)", inUse, pooled, 0, 0)
+	}
+
+	seq := []keyType{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	bulkInsert(tree, seq)
+	if got := getIterSeq(tree.NewIterator()); !checkIterSeq(got, seq) {
+		t.Fatalf("unexpected sequence %v; want %v", got, seq)
+	}
+}
+
+// Allocator handing out nodes from slabs and keeping a free list.
+type slabAllocator struct {
+	slab  []avltree.Node[keyType, valType]
+	free  []*avltree.Node[keyType, valType]
+	inUse int
+}
+
+func (a *slabAllocator) Get() *avltree.Node[keyType, valType] {
+	a.inUse++
+	if n := len(a.free); n > 0 {
+		node := a.free[n-1]
+		a.free = a.free[:n-1]
+		return node
+	}
+	if len(a.slab) == cap(a.slab) {
+		a.slab = make([]avltree.Node[keyType, valType], 0, 4)
+	}
+	a.slab = a.slab[:len(a.slab)+1]
+	return &a.slab[len(a.slab)-1]
+}
+
+func (a *slabAllocator) Put(node *avltree.Node[keyType, valType]) {
+	a.inUse--
+	a.free = append(a.free, node)
+}
+
+// Trees using a custom allocator should get and put all nodes through it.
+func TestAllocator(t *testing.T) {
+	alloc := &slabAllocator{}
+	seq := []keyType{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	tree := newTree(seq, avltree.WithAllocator[keyType, valType](alloc))
+	bulkRemove(tree, []keyType{2, 4, 6})
+	bulkInsert(tree, []keyType{10, 11})
+	if got, want := alloc.inUse, tree.Length(); got != want {
+		t.Fatalf("allocator nodes in use = %d; want %d", got, want)
+	}
+	if balanced, sorted := tree.Validate(); !balanced || !sorted {
+		t.Fatalf("tree.Validate() = %v, %v; want true, true", balanced, sorted)
+	}
+	if inUse, pooled := tree.PoolStats(); inUse != 8 || pooled != 0 {
+		t.Fatalf("tree.PoolStats() = (%d, %d); want (%d, %d)", inUse, pooled, 8, 0)
+	}
+
+	// Clones share the allocator.
+	clone := tree.Clone()
+	if got, want := alloc.inUse, 2*tree.Length(); got != want {
+		t.Fatalf("allocator nodes in use = %d; want %d", got, want)
+	}
+	clone.Clear(nil)
+	tree.Clear(nil)
+	if got, want := alloc.inUse, 0; got != want {
+		t.Fatalf("allocator nodes in use = %d; want %d", got, want)
+	}
+}
+
+// The changed since iterator should only visit associations modified after a
+// given sequence number.
+func TestChangedSinceIterator(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 3, 4, 5}, avltree.WithSequence[keyType, valType]())
+	if got, want := tree.Sequence(), uint64(5); got != want {
+		t.Fatalf("tree.Sequence() = %d; want %d", got, want)
+	}
+
+	since := tree.Sequence()
+	tree.Add(4, 4)
+	avltree.Increment(tree, 2, 0)
+	tree.Add(6, 6)
+	tree.Remove(1) // Moves associations between nodes
+
+	want := []keyType{2, 4, 6}
+	changed := tree.NewChangedSinceIterator(since)
+	var got []assoc
+	for k, v, ok := changed.Next(); ok; k, v, ok = changed.Next() {
+		got = append(got, assoc{k, v})
+	}
+	if !checkIterSeq(got, want) {
+		t.Fatalf("got sequence %v; want %v", got, want)
+	}
+
+	// All associations are newer than sequence number zero.
+	changed = tree.NewChangedSinceIterator(0)
+	n := 0
+	for _, _, ok := changed.Next(); ok; _, _, ok = changed.Next() {
+		n++
+	}
+	if n != tree.Length() {
+		t.Fatalf("changed association count %d; want %d", n, tree.Length())
+	}
+
+	// Replacing a key should count as a modification.
+	since = tree.Sequence()
+	tree.UpdateKey(3, 3)
+	if got, want := tree.Sequence(), since+1; got != want {
+		t.Fatalf("tree.Sequence() after UpdateKey = %d; want %d", got, want)
+	}
+
+	// Closing an iterator stopped early should unregister it from the tree.
+	changed = tree.NewChangedSinceIterator(since)
+	if got, want := kvResultString(changed.Next()), kvResultString(3, 3, true); got != want {
+		t.Fatalf("changed.Next() = %v; want %v", got, want)
+	}
+	changed.Close()
+	if got := tree.TrackedIterators(); got != 0 {
+		t.Fatalf("tree.TrackedIterators() = %d after Close; want 0", got)
+	}
+}
+
+// The rank range iterator should visit associations between two ranks.
+func TestRankRangeIterator(t *testing.T) {
+	seq := []keyType{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	testData := []struct {
+		lo, hi int
+		want   []keyType
+	}{
+		{0, 9, seq},
+		{-5, 20, seq},
+		{2, 5, []keyType{3, 4, 5}},
+		{8, 9, []keyType{9}},
+		{5, 5, []keyType{}},
+		{6, 2, []keyType{}},
+		{9, 12, []keyType{}},
+	}
+	for _, td := range testData {
+		tree := newTree(seq)
+		if got := getIterSeq(tree.NewRankRangeIterator(td.lo, td.hi)); !checkIterSeq(got, td.want) {
+			t.Fatalf("tree.NewRankRangeIterator(%d, %d) sequence %v; want %v", td.lo, td.hi, got, td.want)
+		}
+	}
+
+	// Modifications during iteration should respect the bound.
+	tree := newTree(seq)
+	iter := tree.NewRankRangeIterator(2, 5)
+	iter.Next()
+	bulkRemove(tree, []keyType{4})
+	bulkInsert(tree, []keyType{10})
+	want := []keyType{5}
+	if got := getIterSeq(iter); !checkIterSeq(got, want) {
+		t.Fatalf("unexpected sequence %v; want %v", got, want)
+	}
+}
+
+// ApplyRange should visit the keys in the inclusive range.
+func TestApplyRange(t *testing.T) {
+	tree := newTree([]keyType{1, 3, 5, 7, 9})
+	var got []assoc
+	tree.ApplyRange(2, 7, func(k keyType, v valType) {
+		got = append(got, assoc{k, v})
+	})
+	if want := []keyType{3, 5, 7}; !checkIterSeq(got, want) {
+		t.Fatalf("tree.ApplyRange(2, 7) visited %v; want %v", got, want)
+	}
+}
+
+// Range iterators should visit keys in the inclusive range in both directions.
+func TestRangeIterator(t *testing.T) {
+	tree := newTree([]keyType{1, 3, 5, 7, 9})
+	testData := []struct {
+		lo, hi keyType
+		want   []keyType
+	}{
+		{0, 10, []keyType{1, 3, 5, 7, 9}},
+		{3, 7, []keyType{3, 5, 7}},
+		{2, 8, []keyType{3, 5, 7}},
+		{5, 5, []keyType{5}},
+		{4, 4, []keyType{}},
+		{7, 3, []keyType{}},
+		{10, 20, []keyType{}},
+	}
+	for _, td := range testData {
+		if got := getIterSeq(tree.NewRangeIterator(td.lo, td.hi)); !checkIterSeq(got, td.want) {
+			t.Fatalf("tree.NewRangeIterator(%d, %d) sequence %v; want %v", td.lo, td.hi, got, td.want)
+		}
+		reversed := make([]keyType, len(td.want))
+		for i, k := range td.want {
+			reversed[len(reversed)-1-i] = k
+		}
+		if got := getIterSeq(tree.NewRangeReverseIterator(td.lo, td.hi)); !checkIterSeq(got, reversed) {
+			t.Fatalf("tree.NewRangeReverseIterator(%d, %d) sequence %v; want %v", td.lo, td.hi, got, reversed)
+		}
+	}
+
+	// Modifications during iteration should respect the bound.
+	iter := tree.NewRangeIterator(2, 8)
+	iter.Next()
+	bulkRemove(tree, []keyType{5})
+	bulkInsert(tree, []keyType{4, 8, 10})
+	want := []keyType{7, 8}
+	if got := getIterSeq(iter); !checkIterSeq(got, want) {
+		t.Fatalf("unexpected sequence %v; want %v", got, want)
+	}
+}
+
+// Seek should reposition iterators in their direction of travel.
+func TestIteratorSeek(t *testing.T) {
+	testData := []struct {
+		name    string
+		reverse bool
+		key     keyType
+		want    []keyType
+	}{
+		{"Existing", false, 5, []keyType{5, 7, 9}},
+		{"Missing", false, 4, []keyType{5, 7, 9}},
+		{"Backwards", false, 1, []keyType{1, 3, 5, 7, 9}},
+		{"PastEnd", false, 10, []keyType{}},
+		{"ReverseExisting", true, 5, []keyType{5, 3, 1}},
+		{"ReverseMissing", true, 6, []keyType{5, 3, 1}},
+		{"ReversePastEnd", true, 0, []keyType{}},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			tree := newTree([]keyType{1, 3, 5, 7, 9})
+			iter := tree.NewIterator()
+			if td.reverse {
+				iter = tree.NewReverseIterator()
+			}
+			iter.Next()
+			iter.Next()
+			iter.Seek(td.key)
+			if got := getIterSeq(iter); !checkIterSeq(got, td.want) {
+				t.Fatalf("iter.Seek(%d) sequence %v; want %v", td.key, got, td.want)
+			}
+		})
+	}
+
+	// Seeking after modifications and on closed iterators.
+	tree := newTree([]keyType{1, 3, 5, 7, 9})
+	iter := tree.NewIterator()
+	iter.Next()
+	bulkRemove(tree, []keyType{5})
+	iter.Seek(4)
+	want := []keyType{7, 9}
+	if got := getIterSeq(iter); !checkIterSeq(got, want) {
+		t.Fatalf("iter.Seek(4) after Remove sequence %v; want %v", got, want)
+	}
+	iter.Seek(1)
+	if got, want := kvResultString(iter.Next()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("closed iter.Seek(1); iter.Next() = %v; want %v", got, want)
+	}
+
+	// Bounded iterators should not be moved outside of their range.
+	tree = newTree([]keyType{1, 3, 5, 7, 9})
+	bounded := []struct {
+		name string
+		iter *iterType
+		key  keyType
+		want []keyType
+	}{
+		{"Range", tree.NewRangeIterator(5, 7), 1, []keyType{5, 7}},
+		{"RangeMissingStart", tree.NewRangeIterator(4, 7), 1, []keyType{5, 7}},
+		{"RangeInside", tree.NewRangeIterator(3, 7), 6, []keyType{7}},
+		{"ReverseRange", tree.NewRangeReverseIterator(3, 5), 9, []keyType{5, 3}},
+		{"RankRange", tree.NewRankRangeIterator(2, 4), 1, []keyType{5, 7}},
+	}
+	for _, td := range bounded {
+		td.iter.Next()
+		td.iter.Seek(td.key)
+		if got := getIterSeq(td.iter); !checkIterSeq(got, td.want) {
+			t.Fatalf("%s iter.Seek(%d) sequence %v; want %v", td.name, td.key, got, td.want)
+		}
+	}
+}
+
+// Peek should return the association of the following Next without advancing.
+func TestIteratorPeek(t *testing.T) {
+	tree := newTree([]keyType{1, 3, 5, 7, 9})
+	iter := tree.NewIterator()
+	for i := 0; i < 2; i++ {
+		if got, want := kvResultString(iter.Peek()), kvResultString(1, 1, true); got != want {
+			t.Fatalf("iter.Peek() = %v; want %v", got, want)
+		}
+	}
+	iter.Next()
+
+	// Removing the peeked association should move the iterator.
+	bulkRemove(tree, []keyType{3})
+	if got, want := kvResultString(iter.Peek()), kvResultString(5, 5, true); got != want {
+		t.Fatalf("iter.Peek() after Remove = %v; want %v", got, want)
+	}
+	if got, want := kvResultString(iter.Next()), kvResultString(5, 5, true); got != want {
+		t.Fatalf("iter.Next() after Peek = %v; want %v", got, want)
+	}
+	want := []keyType{7, 9}
+	if got := getIterSeq(iter); !checkIterSeq(got, want) {
+		t.Fatalf("unexpected sequence %v; want %v", got, want)
+	}
+	if got, want := kvResultString(iter.Peek()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("closed iter.Peek() = %v; want %v", got, want)
+	}
+
+	// Bounded iterators should not peek past their bound.
+	iter = tree.NewRangeIterator(1, 6)
+	iter.Next()
+	iter.Next()
+	if got, want := kvResultString(iter.Peek()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("iter.Peek() past bound = %v; want %v", got, want)
+	}
+}
+
+// Cloned iterators should advance independently and be updated by the tree.
+func TestIteratorClone(t *testing.T) {
+	tree := newTree([]keyType{1, 3, 5, 7, 9})
+	iter := tree.NewIterator()
+	iter.Next()
+	clone := iter.Clone()
+	clone.Next()
+	clone.Next()
+
+	bulkRemove(tree, []keyType{3, 7})
+	bulkInsert(tree, []keyType{8})
+	if got, want := getIterSeq(iter), []keyType{5, 8, 9}; !checkIterSeq(got, want) {
+		t.Fatalf("iter sequence %v; want %v", got, want)
+	}
+	if got, want := getIterSeq(clone), []keyType{9}; !checkIterSeq(got, want) {
+		t.Fatalf("clone sequence %v; want %v", got, want)
+	}
+
+	// Cloning should preserve the direction and bound.
+	iter = tree.NewRangeReverseIterator(2, 8)
+	iter.Next()
+	if got, want := getIterSeq(iter.Clone()), []keyType{5}; !checkIterSeq(got, want) {
+		t.Fatalf("reverse clone sequence %v; want %v", got, want)
+	}
+	iter.Close()
+	if got, want := kvResultString(iter.Clone().Next()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("closed iter.Clone().Next() = %v; want %v", got, want)
+	}
+}
+
+// Iterators created on a small tree should keep working as the tree grows.
+func TestIteratorTreeGrowth(t *testing.T) {
+	tree := newTree([]keyType{0})
+	iter := tree.NewIterator()
+	var want []keyType
+	for k := keyType(0); k < 1000; k++ {
+		tree.Add(k, valType(k))
+		want = append(want, k)
+	}
+	if got := getIterSeq(iter); !checkIterSeq(got, want) {
+		t.Fatalf("unexpected sequence %v; want %v", got, want)
+	}
+}
+
+// Snapshot iterators should not be affected by modifications of the tree.
+func TestSnapshotIterator(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 3, 4, 5}, avltree.WithSyncPool[keyType, valType]())
+	iter := tree.NewSnapshotIterator()
+	reverse := tree.NewSnapshotReverseIterator()
+	iter.Next()
+
+	bulkRemove(tree, []keyType{2, 3})
+	bulkInsert(tree, []keyType{6})
+	tree.Add(4, 40)
+
+	if want := []keyType{2, 3, 4, 5}; !checkIterSeq(getIterSeq(iter), want) {
+		t.Fatalf("snapshot iterator sequence changed; want %v", want)
+	}
+	got := getIterSeq(reverse)
+	if want := "[{5 5} {4 4} {3 3} {2 2} {1 1}]"; fmt.Sprint(got) != want {
+		t.Fatalf("snapshot reverse iterator sequence %v; want %v", got, want)
+	}
+}
+
+// Untracked iterators should visit all associations and be safe to close.
+func TestUntrackedIterator(t *testing.T) {
+	seq := []keyType{1, 2, 3, 4, 5}
+	tree := newTree(seq)
+	iter := tree.NewUntrackedIterator()
+	if got := getIterSeq(iter); !checkIterSeq(got, seq) {
+		t.Fatalf("got sequence %v; want %v", got, seq)
+	}
+	iter.Close()
+	if got, want := kvResultString(iter.Next()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("iter.Next() = %v; want %v", got, want)
+	}
+
+	iter = tree.NewUntrackedIterator()
+	iter.Next()
+	iter.Close()
+	if got, want := kvResultString(iter.Next()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("iter.Close: iter.Next() = %v; want %v", got, want)
+	}
+
+	if got := getIterSeq(newTree(nil).NewUntrackedIterator()); len(got) != 0 {
+		t.Fatalf("got sequence %v; want []", got)
+	}
+}
+
+// ArgMin and ArgMax should pick the lowest key among equal scores.
+func TestArgMinMax(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 3, 4, 5, 6})
+	score := func(k keyType, _ valType) int { return int(k-3) * int(k-4) }
+
+	if got, want := kvResultString(tree.ArgMin(score)), kvResultString(3, 3, true); got != want {
+		t.Fatalf("tree.ArgMin() = %v; want %v", got, want)
+	}
+	if got, want := kvResultString(tree.ArgMax(score)), kvResultString(1, 1, true); got != want {
+		t.Fatalf("tree.ArgMax() = %v; want %v", got, want)
+	}
+	if got, want := kvResultString(newTree(nil).ArgMin(score)), kvResultString(0, 0, false); got != want {
+		t.Fatalf("empty tree.ArgMin() = %v; want %v", got, want)
+	}
+}
+
+// FirstGapAtLeast should find the first sufficiently large gap between keys.
+func TestFirstGapAtLeast(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 4, 7, 8, 12})
+	sub := func(hi, lo keyType) keyType { return hi - lo }
+
+	testData := []struct {
+		d    keyType
+		want string
+	}{
+		{1, "1 2 true"},
+		{2, "2 4 true"},
+		{3, "4 7 true"},
+		{4, "8 12 true"},
+		{5, "0 0 false"},
+	}
+	for _, td := range testData {
+		lo, hi, ok := tree.FirstGapAtLeast(td.d, sub)
+		if got := fmt.Sprint(lo, hi, ok); got != td.want {
+			t.Fatalf("tree.FirstGapAtLeast(%d) = %v; want %v", td.d, got, td.want)
+		}
+	}
+
+	if _, _, ok := newTree([]keyType{1}).FirstGapAtLeast(0, sub); ok {
+		t.Fatalf("tree.FirstGapAtLeast(0) on single key tree = true; want false")
+	}
+}
+
+// Shuffled iterators should visit each association once in a reproducible
+// order.
+func TestShuffledIterator(t *testing.T) {
+	var keys []keyType
+	for k := keyType(0); k < 100; k++ {
+		keys = append(keys, k)
+	}
+	tree := newTree(keys)
+
+	shuffle := func(seed int64) (seq []keyType) {
+		iter := tree.NewShuffledIterator(rand.New(rand.NewSource(seed)))
+		for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
+			if keyType(v) != k {
+				t.Fatalf("iter.Next() = %v,%v,%v; want value %v", k, v, ok, k)
+			}
+			seq = append(seq, k)
+		}
+		return
+	}
+
+	first, second := shuffle(1), shuffle(1)
+	if fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Fatalf("shuffles with the same seed differ: %v and %v", first, second)
+	}
+	if fmt.Sprint(first) == fmt.Sprint(keys) {
+		t.Fatalf("shuffle %v is sorted", first)
+	}
+	sort.Slice(first, func(i, j int) bool { return first[i] < first[j] })
+	if fmt.Sprint(first) != fmt.Sprint(keys) {
+		t.Fatalf("sorted shuffle = %v; want %v", first, keys)
+	}
+
+	empty := newTree(nil).NewShuffledIterator(rand.New(rand.NewSource(1)))
+	if got, want := kvResultString(empty.Next()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("empty.Next() = %v; want %v", got, want)
+	}
+}
+
+// The gap iterator should report each missing run of keys once.
+func TestGapIterator(t *testing.T) {
+	testData := []struct {
+		keys []keyType
+		want string
+	}{
+		{nil, "[]"},
+		{[]keyType{1}, "[]"},
+		{[]keyType{1, 2, 3}, "[]"},
+		{[]keyType{1, 2, 5, 6, 9}, "[[3 5] [7 9]]"},
+		{[]keyType{0, 2, 4}, "[[1 2] [3 4]]"},
+	}
+
+	for i, td := range testData {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			tree := newTree(td.keys)
+			gaps := tree.NewGapIterator(func(k keyType) keyType { return k + 1 })
+
+			var got [][2]keyType
+			for lo, hi, ok := gaps.Next(); ok; lo, hi, ok = gaps.Next() {
+				got = append(got, [2]keyType{lo, hi})
+			}
+			if s := fmt.Sprint(got); s != td.want {
+				t.Fatalf("gap sequence of %v = %s; want %s", td.keys, s, td.want)
+			}
+		})
+	}
+
+	// Closing a gap iterator stopped early should unregister it from the tree.
+	tree := newTree([]keyType{1, 3, 5, 7})
+	gaps := tree.NewGapIterator(func(k keyType) keyType { return k + 1 })
+	gaps.Next()
+	gaps.Close()
+	if got := tree.TrackedIterators(); got != 0 {
+		t.Fatalf("tree.TrackedIterators() = %d after Close; want 0", got)
 	}
 }
 
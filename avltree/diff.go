@@ -0,0 +1,132 @@
+package avltree
+
+import (
+	"github.com/johan-bolmsjo/gods/v2/math"
+)
+
+// Change describes how the association of a key differs between two trees.
+// Old is the zero value of V for added keys and New is the zero value of V for
+// removed keys.
+type Change[K, V any] struct {
+	Key      K
+	Old, New V
+}
+
+// Diff compares two trees and classifies each key as added (only in after),
+// removed (only in before) or changed (in both but with values that valueEqual
+// reports as different). Keys are ordered by cmp which must be consistent with
+// the compare functions of both trees. The changes are returned in ascending
+// key order. Diff walks both trees once in O(n+m) time.
+func Diff[K, V any](before, after *Tree[K, V], cmp math.Comparator[K], valueEqual func(a, b V) bool) (added, removed, changed []Change[K, V]) {
+	biter, aiter := before.NewIterator(), after.NewIterator()
+	defer biter.Close()
+	defer aiter.Close()
+
+	bk, bv, bok := biter.Next()
+	ak, av, aok := aiter.Next()
+
+	for bok || aok {
+		var c int
+		switch {
+		case !aok:
+			c = -1
+		case !bok:
+			c = 1
+		default:
+			c = cmp(bk, ak)
+		}
+
+		switch {
+		case c < 0:
+			removed = append(removed, Change[K, V]{Key: bk, Old: bv})
+			bk, bv, bok = biter.Next()
+		case c > 0:
+			added = append(added, Change[K, V]{Key: ak, New: av})
+			ak, av, aok = aiter.Next()
+		default:
+			if !valueEqual(bv, av) {
+				changed = append(changed, Change[K, V]{Key: ak, Old: bv, New: av})
+			}
+			bk, bv, bok = biter.Next()
+			ak, av, aok = aiter.Next()
+		}
+	}
+	return
+}
+
+// JaccardIndex returns the size of the intersection of the key sets of a and b
+// divided by the size of their union. Keys are ordered by cmp which must be
+// consistent with the compare functions of both trees. Zero is returned if both
+// trees are empty. JaccardIndex walks both trees once in O(n+m) time.
+func JaccardIndex[K any](a, b *Tree[K, struct{}], cmp math.Comparator[K]) float64 {
+	aiter, biter := a.NewIterator(), b.NewIterator()
+	defer aiter.Close()
+	defer biter.Close()
+
+	ak, _, aok := aiter.Next()
+	bk, _, bok := biter.Next()
+
+	var intersection, union int
+	for aok && bok {
+		union++
+		switch c := cmp(ak, bk); {
+		case c < 0:
+			ak, _, aok = aiter.Next()
+		case c > 0:
+			bk, _, bok = biter.Next()
+		default:
+			intersection++
+			ak, _, aok = aiter.Next()
+			bk, _, bok = biter.Next()
+		}
+	}
+	for ; aok; _, _, aok = aiter.Next() {
+		union++
+	}
+	for ; bok; _, _, bok = biter.Next() {
+		union++
+	}
+
+	if union == 0 {
+		return 0
+	}
+	return float64(intersection) / float64(union)
+}
+
+// EqualToMap reports whether the associations of tree are exactly those of m,
+// with values compared by valueEqual. Keys are looked up in m using the ==
+// operator. The tree is walked once.
+func EqualToMap[K comparable, V any](tree *Tree[K, V], m map[K]V, valueEqual func(a, b V) bool) bool {
+	if tree.Length() != len(m) {
+		return false
+	}
+	equal := true
+	tree.walkNodes(func(n *node[K, V]) bool {
+		v, ok := m[n.key]
+		equal = ok && valueEqual(n.value, v)
+		return equal
+	})
+	return equal
+}
+
+// Equal reports whether the tree and other hold associations for the same keys,
+// according to the compare function of the tree, with values that valueEqual
+// reports as equal. Only keys are compared if valueEqual is nil. Both trees are
+// walked in lockstep in O(n) time unless their lengths differ.
+func (tree *Tree[K, V]) Equal(other *Tree[K, V], valueEqual func(a, b V) bool) bool {
+	if tree.Length() != other.Length() {
+		return false
+	}
+
+	titer, oiter := tree.NewIterator(), other.NewIterator()
+	defer titer.Close()
+	defer oiter.Close()
+
+	for k, v, ok := titer.Next(); ok; k, v, ok = titer.Next() {
+		otherKey, otherValue, _ := oiter.Next()
+		if tree.compareKeys(k, otherKey) != 0 || (valueEqual != nil && !valueEqual(v, otherValue)) {
+			return false
+		}
+	}
+	return true
+}
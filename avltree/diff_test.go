@@ -0,0 +1,124 @@
+package avltree_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/johan-bolmsjo/gods/v2/avltree"
+	"github.com/johan-bolmsjo/gods/v2/math"
+)
+
+// Diff should classify keys as added, removed or changed.
+func TestDiff(t *testing.T) {
+	before := newTree([]keyType{1, 2, 3, 5, 8})
+	after := newTree([]keyType{2, 3, 4, 8, 9})
+	after.Add(3, 30)
+	after.Add(8, 80)
+
+	added, removed, changed := avltree.Diff(before, after, math.CompareOrdered[keyType],
+		func(a, b valType) bool { return a == b })
+
+	testData := []struct {
+		name string
+		got  []avltree.Change[keyType, valType]
+		want string
+	}{
+		{"added", added, "[{4 0 4} {9 0 9}]"},
+		{"removed", removed, "[{1 1 0} {5 5 0}]"},
+		{"changed", changed, "[{3 3 30} {8 8 80}]"},
+	}
+	for _, td := range testData {
+		if got := fmt.Sprint(td.got); got != td.want {
+			t.Fatalf("%s = %s; want %s", td.name, got, td.want)
+		}
+	}
+}
+
+// Diffing empty trees should produce no changes.
+func TestDiffEmpty(t *testing.T) {
+	added, removed, changed := avltree.Diff(newTree(nil), newTree(nil), math.CompareOrdered[keyType],
+		func(a, b valType) bool { return a == b })
+	if added != nil || removed != nil || changed != nil {
+		t.Fatalf("avltree.Diff() = (%v, %v, %v); want no changes", added, removed, changed)
+	}
+}
+
+// JaccardIndex should divide the intersection size by the union size.
+func TestJaccardIndex(t *testing.T) {
+	newSet := func(keys []int) *avltree.Tree[int, struct{}] {
+		set := avltree.New[int, struct{}](math.CompareOrdered[int])
+		for _, k := range keys {
+			set.Add(k, struct{}{})
+		}
+		return set
+	}
+
+	testData := []struct {
+		a, b []int
+		want float64
+	}{
+		{nil, nil, 0},
+		{[]int{1, 2}, nil, 0},
+		{[]int{1, 2, 3}, []int{1, 2, 3}, 1},
+		{[]int{1, 2, 3, 5}, []int{2, 3, 4}, 0.4},
+		{[]int{1, 3}, []int{2, 4}, 0},
+	}
+	for _, td := range testData {
+		got := avltree.JaccardIndex(newSet(td.a), newSet(td.b), math.CompareOrdered[int])
+		if got != td.want {
+			t.Fatalf("avltree.JaccardIndex(%v, %v) = %v; want %v", td.a, td.b, got, td.want)
+		}
+	}
+}
+
+// EqualToMap should require the same keys and equal values.
+func TestEqualToMap(t *testing.T) {
+	tree := newTree([]keyType{1, 2, 3})
+	valueEqual := func(a, b valType) bool { return a == b }
+
+	testData := []struct {
+		m    map[keyType]valType
+		want bool
+	}{
+		{map[keyType]valType{1: 1, 2: 2, 3: 3}, true},
+		{map[keyType]valType{1: 1, 2: 2}, false},
+		{map[keyType]valType{1: 1, 2: 2, 3: 3, 4: 4}, false},
+		{map[keyType]valType{1: 1, 2: 2, 4: 4}, false},
+		{map[keyType]valType{1: 1, 2: 20, 3: 3}, false},
+	}
+	for _, td := range testData {
+		if got := avltree.EqualToMap(tree, td.m, valueEqual); got != td.want {
+			t.Fatalf("avltree.EqualToMap(tree, %v) = %v; want %v", td.m, got, td.want)
+		}
+	}
+
+	if !avltree.EqualToMap(newTree(nil), map[keyType]valType{}, valueEqual) {
+		t.Fatalf("avltree.EqualToMap(empty, {}) = false; want true")
+	}
+}
+
+// Equal should compare keys and, if requested, values.
+func TestEqual(t *testing.T) {
+	valueEqual := func(a, b valType) bool { return a == b }
+	tree := newTree([]keyType{1, 2, 3})
+	changed := newTree([]keyType{1, 2, 3})
+	changed.Add(2, 20)
+
+	testData := []struct {
+		name       string
+		other      *avltree.Tree[keyType, valType]
+		valueEqual func(a, b valType) bool
+		want       bool
+	}{
+		{"Same", newTree([]keyType{3, 2, 1}), valueEqual, true},
+		{"Shorter", newTree([]keyType{1, 2}), valueEqual, false},
+		{"OtherKeys", newTree([]keyType{1, 2, 4}), valueEqual, false},
+		{"OtherValues", changed, valueEqual, false},
+		{"KeysOnly", changed, nil, true},
+	}
+	for _, td := range testData {
+		if got := tree.Equal(td.other, td.valueEqual); got != td.want {
+			t.Fatalf("%s: tree.Equal() = %v; want %v", td.name, got, td.want)
+		}
+	}
+}
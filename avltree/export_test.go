@@ -0,0 +1,88 @@
+package avltree
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/johan-bolmsjo/gods/v2/list"
+)
+
+// AssertPooledNodesCleared exports assertPooledNodesCleared to tests.
+func (tree *Tree[K, V]) AssertPooledNodesCleared() error {
+	return tree.assertPooledNodesCleared()
+}
+
+// TrackedIterators returns the number of iterators registered with the tree
+// for updates.
+func (tree *Tree[K, V]) TrackedIterators() int {
+	return list.Count(&tree.iters, nil)
+}
+
+// MultiMap exports the underlying tree of a multimap to tests.
+func (m *MultiMap[K, V]) Tree() *Tree[K, []V] {
+	return m.tree
+}
+
+// Check that nodes held by the node pool or arena of the tree for reuse don't
+// retain any links, keys or values. Nodes taken from the pool are put back
+// when done.
+func (tree *Tree[K, V]) assertPooledNodesCleared() error {
+	if arena := tree.arena; arena != nil {
+		i := 0
+		for n := arena.free; n != nil; n = n.link[directionRight] {
+			if err := assertNodeCleared(n, n.link[directionLeft]); err != nil {
+				return fmt.Errorf("arena free list node %d: %w", i, err)
+			}
+			i++
+		}
+		return nil
+	}
+
+	if pool, ok := tree.allocator.(*nodePool[K, V]); ok {
+		// Bypass the counters of the pool to leave PoolStats unaffected. The
+		// pool is drained once a node is freshly allocated, as the garbage
+		// collector may have dropped pooled nodes. Such nodes are discarded.
+		news := atomic.LoadInt64(&pool.news)
+		defer atomic.StoreInt64(&pool.news, news)
+
+		var nodes []*node[K, V]
+		for i := pool.pooled(); i > 0; i-- {
+			n := pool.pool.Get().(*Node[K, V])
+			if atomic.LoadInt64(&pool.news) != news {
+				break
+			}
+			nodes = append(nodes, (*node[K, V])(n))
+		}
+		defer func() {
+			for _, n := range nodes {
+				pool.pool.Put((*Node[K, V])(n))
+			}
+		}()
+
+		for i, n := range nodes {
+			if err := assertNodeCleared(n, n.link[directionLeft], n.link[directionRight]); err != nil {
+				return fmt.Errorf("pooled node %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func assertNodeCleared[K, V any](n *node[K, V], links ...*node[K, V]) error {
+	for _, link := range links {
+		if link != nil {
+			return fmt.Errorf("retains link to %p", link)
+		}
+	}
+	if n.balance != 0 || n.size != 0 || n.seq != 0 {
+		return fmt.Errorf("has balance %d, size %d and sequence %d", n.balance, n.size, n.seq)
+	}
+	if !reflect.ValueOf(&n.key).Elem().IsZero() {
+		return fmt.Errorf("retains key %v", n.key)
+	}
+	if !reflect.ValueOf(&n.value).Elem().IsZero() {
+		return fmt.Errorf("retains value %v", n.value)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package avltree
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+)
+
+// Gob representation of an association.
+type gobAssoc[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// GobEncode encodes the associations of the tree in ascending key order. Only
+// keys and values are encoded, not the structure of the tree. Keys and values
+// are encoded by encoding/gob.
+func (tree *Tree[K, V]) GobEncode() ([]byte, error) {
+	assocs := make([]gobAssoc[K, V], 0, tree.length)
+	tree.walkNodes(func(n *node[K, V]) bool {
+		assocs = append(assocs, gobAssoc[K, V]{Key: n.key, Value: n.value})
+		return true
+	})
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(assocs); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode decodes associations encoded by GobEncode and adds them to the tree.
+// The tree must have been created by New as the compare function is needed to
+// order the associations; an error is returned for the zero value of Tree.
+// Existing associations of the tree are kept unless overwritten. See also
+// DecodeInto.
+func (tree *Tree[K, V]) GobDecode(data []byte) error {
+	if tree.compareKeys == nil {
+		return errNoCompareFunc
+	}
+	var assocs []gobAssoc[K, V]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&assocs); err != nil {
+		return err
+	}
+	return tree.withAppendOptimized(func() error {
+		for _, a := range assocs {
+			tree.Add(a.Key, a.Value)
+		}
+		return nil
+	})
+}
+
+// DecodeInto reads a tree encoded by a gob.Encoder from r and adds its
+// associations to tree like GobDecode. This is a convenience for
+// gob.NewDecoder(r).Decode(tree), which works as tree is already created. The
+// tree must be the first value written by the encoder as type information is
+// only sent once per encoder; decode streams of several values using a shared
+// gob.Decoder instead.
+func DecodeInto[K, V any](tree *Tree[K, V], r io.Reader) error {
+	return gob.NewDecoder(r).Decode(tree)
+}
@@ -0,0 +1,48 @@
+package avltree_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"testing"
+
+	"github.com/johan-bolmsjo/gods/v2/avltree"
+)
+
+// Trees should round-trip through a gob encoder and decoder.
+func TestGob(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(newTree([]keyType{3, 1, 2})); err != nil {
+		t.Fatalf("enc.Encode(tree) = %v", err)
+	}
+	tree := newTree([]keyType{4})
+	if err := avltree.DecodeInto(tree, &buf); err != nil {
+		t.Fatalf("avltree.DecodeInto(tree) = %v", err)
+	}
+	if got, want := fmt.Sprint(getIterSeq(tree.NewIterator())), "[{1 1} {2 2} {3 3} {4 4}]"; got != want {
+		t.Fatalf("avltree.DecodeInto(tree) sequence %v; want %v", got, want)
+	}
+	if balanced, sorted := tree.Validate(); !balanced || !sorted {
+		t.Fatalf("tree.Validate() = %v, %v; want true, true", balanced, sorted)
+	}
+
+	// Several trees can be sent using shared encoders and decoders.
+	enc, dec := gob.NewEncoder(&buf), gob.NewDecoder(&buf)
+	for _, keys := range [][]keyType{nil, {5, 6}} {
+		if err := enc.Encode(newTree(keys)); err != nil {
+			t.Fatalf("enc.Encode(tree) = %v", err)
+		}
+		decoded := newTree(nil)
+		if err := dec.Decode(decoded); err != nil {
+			t.Fatalf("dec.Decode(tree) = %v", err)
+		}
+		if got, want := getIterSeq(decoded.NewIterator()), keys; !checkIterSeq(got, want) {
+			t.Fatalf("dec.Decode(tree) sequence %v; want %v", got, want)
+		}
+	}
+
+	var zero treeType
+	if err := zero.GobDecode(nil); err == nil {
+		t.Fatalf("zero.GobDecode() = nil; want error")
+	}
+}
@@ -0,0 +1,98 @@
+package avltree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// errNotJSONArray is returned when a JSON array was expected but not found.
+var errNotJSONArray = errors.New("avltree: JSON input is not an array")
+
+// errNoCompareFunc is returned when unmarshaling into a tree not created by New.
+var errNoCompareFunc = errors.New("avltree: tree has no compare function")
+
+// JSON representation of an association.
+type jsonAssoc struct {
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// JSON representation of an association with typed key and value.
+type jsonTypedAssoc[K, V any] struct {
+	Key   K `json:"key"`
+	Value V `json:"value"`
+}
+
+// MarshalJSON encodes the associations of the tree as a JSON array of
+// {"key": ..., "value": ...} objects in ascending key order. Keys and values
+// are encoded by encoding/json.
+func (tree *Tree[K, V]) MarshalJSON() ([]byte, error) {
+	assocs := make([]jsonTypedAssoc[K, V], 0, tree.length)
+	tree.walkNodes(func(n *node[K, V]) bool {
+		assocs = append(assocs, jsonTypedAssoc[K, V]{Key: n.key, Value: n.value})
+		return true
+	})
+	return json.Marshal(assocs)
+}
+
+// UnmarshalJSON decodes a JSON array in the format produced by MarshalJSON and
+// adds its associations to the tree. Keys and values are decoded by
+// encoding/json. The tree must have been created by New as the compare
+// function is needed to order the associations; an error is returned for the
+// zero value of Tree. Existing associations of the tree are kept unless
+// overwritten.
+func (tree *Tree[K, V]) UnmarshalJSON(data []byte) error {
+	if tree.compareKeys == nil {
+		return errNoCompareFunc
+	}
+	return tree.DecodeJSONStream(bytes.NewReader(data), unmarshalJSON[K], unmarshalJSON[V])
+}
+
+func unmarshalJSON[T any](data json.RawMessage) (v T, err error) {
+	err = json.Unmarshal(data, &v)
+	return
+}
+
+// DecodeJSONStream reads a JSON array of {"key": ..., "value": ...} objects
+// from r and adds each association to the tree as it's decoded, without holding
+// the whole array in memory. Keys and values are decoded by decodeKey and
+// decodeValue. Keys arriving in ascending order are added using the fast path
+// of WithAppendOptimized. Associations decoded before an error occurred remain
+// in the tree. Data following the array may be consumed from r.
+func (tree *Tree[K, V]) DecodeJSONStream(r io.Reader, decodeKey func(json.RawMessage) (K, error), decodeValue func(json.RawMessage) (V, error)) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('[') {
+		return errNotJSONArray
+	}
+
+	err = tree.withAppendOptimized(func() error {
+		for dec.More() {
+			var elem jsonAssoc
+			if err := dec.Decode(&elem); err != nil {
+				return err
+			}
+			key, err := decodeKey(elem.Key)
+			if err != nil {
+				return err
+			}
+			value, err := decodeValue(elem.Value)
+			if err != nil {
+				return err
+			}
+			tree.Add(key, value)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = dec.Token()
+	return err
+}
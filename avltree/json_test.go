@@ -0,0 +1,70 @@
+package avltree_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func decodeJSON[T any](data json.RawMessage) (v T, err error) {
+	err = json.Unmarshal(data, &v)
+	return
+}
+
+// Streamed associations should be added as they are decoded.
+func TestDecodeJSONStream(t *testing.T) {
+	testData := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{`[]`, "[]", false},
+		{`[{"key":1,"value":10},{"key":2,"value":20},{"key":3,"value":30}]`, "[{1 10} {2 20} {3 30}]", false},
+		{`[{"key":3,"value":30},{"key":1,"value":10},{"key":3,"value":31}]`, "[{1 10} {3 31}]", false},
+		{`[{"key":1,"value":10},{"key":"x","value":20}]`, "[{1 10}]", true},
+		{`[{"key":1,"value":10}`, "[{1 10}]", true},
+		{`{"key":1,"value":10}`, "[]", true},
+	}
+	for _, td := range testData {
+		tree := newTree(nil)
+		err := tree.DecodeJSONStream(strings.NewReader(td.input), decodeJSON[keyType], decodeJSON[valType])
+		if (err != nil) != td.wantErr {
+			t.Fatalf("tree.DecodeJSONStream(%s) = %v; want error %v", td.input, err, td.wantErr)
+		}
+		if got := fmt.Sprint(getIterSeq(tree.NewIterator())); got != td.want {
+			t.Fatalf("tree.DecodeJSONStream(%s) sequence %v; want %v", td.input, got, td.want)
+		}
+		if balanced, sorted := tree.Validate(); !balanced || !sorted {
+			t.Fatalf("tree.Validate() = %v, %v; want true, true", balanced, sorted)
+		}
+	}
+}
+
+// Trees should round-trip through MarshalJSON and UnmarshalJSON.
+func TestMarshalJSON(t *testing.T) {
+	tree := newTree([]keyType{3, 1, 2})
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("json.Marshal(tree) = %v", err)
+	}
+	if got, want := string(data), `[{"key":1,"value":1},{"key":2,"value":2},{"key":3,"value":3}]`; got != want {
+		t.Fatalf("json.Marshal(tree) = %s; want %s", got, want)
+	}
+	if data, _ := json.Marshal(newTree(nil)); string(data) != "[]" {
+		t.Fatalf("json.Marshal(empty) = %s; want []", data)
+	}
+
+	decoded := newTree([]keyType{4})
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("json.Unmarshal(%s) = %v", data, err)
+	}
+	if got, want := fmt.Sprint(getIterSeq(decoded.NewIterator())), "[{1 1} {2 2} {3 3} {4 4}]"; got != want {
+		t.Fatalf("json.Unmarshal(%s) sequence %v; want %v", data, got, want)
+	}
+
+	var zero treeType
+	if err := json.Unmarshal(data, &zero); err == nil {
+		t.Fatalf("json.Unmarshal(%s) into zero tree = nil; want error", data)
+	}
+}
@@ -0,0 +1,120 @@
+package avltree
+
+import "github.com/johan-bolmsjo/gods/v2/math"
+
+// MultiMap is an ordered map from keys to one or more values. The values of a
+// key are kept in the order they were added.
+type MultiMap[K, V any] struct {
+	tree   *Tree[K, []V]
+	length int
+}
+
+// NewMultiMap creates a multimap using the supplied compare function and tree
+// options for the underlying tree. Panics if given an option that removes
+// associations on its own, such as WithMaxSize or WithMaxSizeFIFO.
+func NewMultiMap[K, V any](compareKeys math.Comparator[K], options ...TreeOption[K, []V]) *MultiMap[K, V] {
+	tree := New(compareKeys, options...)
+	if tree.maxSize > 0 {
+		panic("avltree: multimap does not support options evicting associations")
+	}
+	return &MultiMap[K, V]{tree: tree}
+}
+
+// Add appends value to the values associated with key.
+func (m *MultiMap[K, V]) Add(key K, value V) {
+	node, _, inserted := m.tree.add(key, nil, false)
+	if !inserted {
+		m.tree.stamp(node)
+	}
+	node.value = append(node.value, value)
+	m.length++
+}
+
+// Get returns the values associated with key in the order they were added or
+// nil if there are none. The returned slice must not be modified.
+func (m *MultiMap[K, V]) Get(key K) []V {
+	values, _ := m.tree.Find(key)
+	return values
+}
+
+// RemoveValue removes the first value associated with key for which equal
+// reports true when called with value and returns true. The key is removed when
+// its last value is removed. False is returned if no such value was found.
+func (m *MultiMap[K, V]) RemoveValue(key K, value V, equal func(a, b V) bool) bool {
+	node := m.tree.findNode(key)
+	if node == nil {
+		return false
+	}
+	for i, v := range node.value {
+		if equal(v, value) {
+			if len(node.value) == 1 {
+				m.tree.Remove(key)
+			} else {
+				// Copy to leave slices previously returned by Get intact.
+				values := make([]V, 0, len(node.value)-1)
+				node.value = append(append(values, node.value[:i]...), node.value[i+1:]...)
+				m.tree.stamp(node)
+			}
+			m.length--
+			return true
+		}
+	}
+	return false
+}
+
+// Remove removes all values associated with key.
+func (m *MultiMap[K, V]) Remove(key K) {
+	if values, ok := m.tree.Find(key); ok {
+		m.tree.Remove(key)
+		m.length -= len(values)
+	}
+}
+
+// Length returns the number of key and value pairs in the multimap.
+func (m *MultiMap[K, V]) Length() int {
+	return m.length
+}
+
+// KeyCount returns the number of distinct keys in the multimap.
+func (m *MultiMap[K, V]) KeyCount() int {
+	return m.tree.Length()
+}
+
+// NewIterator creates an iterator over all key and value pairs of the multimap
+// in ascending key order. The values of a key are visited in the order they
+// were added. The values of a key are captured when the iterator reaches the
+// key. Make sure to close the iterator by calling its Close method when done
+// using it unless it's exhausted.
+func (m *MultiMap[K, V]) NewIterator() *MultiMapIterator[K, V] {
+	return &MultiMapIterator[K, V]{iter: m.tree.NewIterator()}
+}
+
+// MultiMapIterator iterates over the key and value pairs of a multimap, see
+// MultiMap.NewIterator.
+type MultiMapIterator[K, V any] struct {
+	iter   *Iterator[K, []V]
+	key    K
+	values []V // Remaining values of key
+}
+
+// Next returns the next key and value pair. The zero values of K and V and
+// false is returned when there are no more pairs.
+func (it *MultiMapIterator[K, V]) Next() (K, V, bool) {
+	for len(it.values) == 0 {
+		k, values, ok := it.iter.Next()
+		if !ok {
+			return zeroAssoc[K, V]()
+		}
+		it.key, it.values = k, values
+	}
+	v := it.values[0]
+	it.values = it.values[1:]
+	return it.key, v, true
+}
+
+// Close invalidates the iterator and removes its reference from the multimap
+// it's associated with. It's safe to call the Next method on closed iterators.
+func (it *MultiMapIterator[K, V]) Close() {
+	it.iter.Close()
+	it.values = nil
+}
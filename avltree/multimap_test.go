@@ -0,0 +1,104 @@
+package avltree_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/johan-bolmsjo/gods/v2/avltree"
+	"github.com/johan-bolmsjo/gods/v2/math"
+)
+
+// Values should be kept per key in insertion order.
+func TestMultiMap(t *testing.T) {
+	m := avltree.NewMultiMap[keyType, valType](math.CompareOrdered[keyType])
+	for _, kv := range []assoc{{2, 20}, {1, 10}, {2, 21}, {3, 30}, {2, 22}} {
+		m.Add(kv.key, kv.val)
+	}
+
+	if got, want := fmt.Sprint(m.Get(2)), "[20 21 22]"; got != want {
+		t.Fatalf("m.Get(2) = %v; want %v", got, want)
+	}
+	if got := m.Get(4); got != nil {
+		t.Fatalf("m.Get(4) = %v; want nil", got)
+	}
+	if got, want := multiMapString(m), "1:10 2:20 2:21 2:22 3:30"; got != want {
+		t.Fatalf("multimap = %v; want %v", got, want)
+	}
+
+	equal := func(a, b valType) bool { return a == b }
+	get := m.Get(2)
+	if !m.RemoveValue(2, 21, equal) {
+		t.Fatalf("m.RemoveValue(2, 21) = false; want true")
+	}
+	if got, want := fmt.Sprint(get), "[20 21 22]"; got != want {
+		t.Fatalf("previous m.Get(2) = %v; want %v", got, want)
+	}
+	if m.RemoveValue(2, 21, equal) || m.RemoveValue(4, 40, equal) {
+		t.Fatalf("m.RemoveValue() = true for missing value; want false")
+	}
+	if !m.RemoveValue(3, 30, equal) {
+		t.Fatalf("m.RemoveValue(3, 30) = false; want true")
+	}
+	if got, want := multiMapString(m), "1:10 2:20 2:22"; got != want {
+		t.Fatalf("multimap = %v; want %v", got, want)
+	}
+	if got, want := fmt.Sprint(m.Length(), m.KeyCount()), "3 2"; got != want {
+		t.Fatalf("m.Length(), m.KeyCount() = %v; want %v", got, want)
+	}
+
+	m.Remove(2)
+	if got, want := fmt.Sprint(m.Length(), m.KeyCount()), "1 1"; got != want {
+		t.Fatalf("m.Length(), m.KeyCount() = %v; want %v", got, want)
+	}
+}
+
+// Appending values should stamp the key and iterators should be closable.
+func TestMultiMapSequence(t *testing.T) {
+	m := avltree.NewMultiMap[keyType, valType](math.CompareOrdered[keyType], avltree.WithSequence[keyType, []valType]())
+	m.Add(1, 10)
+	m.Add(2, 20)
+	since := m.Tree().Sequence()
+	m.Add(1, 11)
+	if got, want := m.Tree().Sequence(), since+1; got != want {
+		t.Fatalf("tree.Sequence() after Add to existing key = %d; want %d", got, want)
+	}
+
+	iter := m.NewIterator()
+	iter.Next()
+	iter.Close()
+	if got := m.Tree().TrackedIterators(); got != 0 {
+		t.Fatalf("tree.TrackedIterators() = %d after Close; want 0", got)
+	}
+	if got, want := kvResultString(iter.Next()), kvResultString(0, 0, false); got != want {
+		t.Fatalf("closed iter.Next() = %v; want %v", got, want)
+	}
+}
+
+// Options evicting associations would break the value count and should panic.
+func TestMultiMapMaxSize(t *testing.T) {
+	for _, option := range []avltree.TreeOption[keyType, []valType]{
+		avltree.WithMaxSize[keyType, []valType](1, nil),
+		avltree.WithMaxSizeFIFO[keyType, []valType](1, nil),
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("avltree.NewMultiMap() with max size did not panic")
+				}
+			}()
+			avltree.NewMultiMap[keyType, valType](math.CompareOrdered[keyType], option)
+		}()
+	}
+}
+
+func multiMapString(m *avltree.MultiMap[keyType, valType]) string {
+	var s string
+	iter := m.NewIterator()
+	for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
+		if s != "" {
+			s += " "
+		}
+		s += fmt.Sprintf("%v:%v", k, v)
+	}
+	return s
+}
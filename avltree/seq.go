@@ -0,0 +1,31 @@
+//go:build go1.23
+
+package avltree
+
+import "iter"
+
+// All returns a range-over-func iterator over the associations of the tree from
+// low to high key values. It's driven by an iterator created by NewIterator
+// which is closed when the loop ends, also when it's ended early.
+func (tree *Tree[K, V]) All() iter.Seq2[K, V] {
+	return tree.rangeFunc(tree.NewIterator)
+}
+
+// Backward returns a range-over-func iterator over the associations of the
+// tree from high to low key values. It's driven by an iterator created by
+// NewReverseIterator like All.
+func (tree *Tree[K, V]) Backward() iter.Seq2[K, V] {
+	return tree.rangeFunc(tree.NewReverseIterator)
+}
+
+func (tree *Tree[K, V]) rangeFunc(newIterator func() *Iterator[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		it := newIterator()
+		defer it.Close()
+		for k, v, ok := it.Next(); ok; k, v, ok = it.Next() {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
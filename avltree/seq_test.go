@@ -0,0 +1,39 @@
+//go:build go1.23
+
+package avltree_test
+
+import "testing"
+
+// Range-over-func iterators should visit associations in both directions.
+func TestAllBackward(t *testing.T) {
+	seq := []keyType{1, 2, 3, 4, 5}
+	tree := newTree(seq)
+
+	var got []assoc
+	for k, v := range tree.All() {
+		got = append(got, assoc{k, v})
+	}
+	if !checkIterSeq(got, seq) {
+		t.Fatalf("tree.All() sequence %v; want %v", got, seq)
+	}
+
+	got = nil
+	for k, v := range tree.Backward() {
+		got = append(got, assoc{k, v})
+	}
+	if want := []keyType{5, 4, 3, 2, 1}; !checkIterSeq(got, want) {
+		t.Fatalf("tree.Backward() sequence %v; want %v", got, want)
+	}
+
+	// Breaking early should stop the iteration.
+	got = nil
+	for k, v := range tree.All() {
+		if k == 3 {
+			break
+		}
+		got = append(got, assoc{k, v})
+	}
+	if want := []keyType{1, 2}; !checkIterSeq(got, want) {
+		t.Fatalf("tree.All() with break sequence %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package avltree
+
+import (
+	"sync"
+
+	"github.com/johan-bolmsjo/gods/v2/math"
+)
+
+// SyncTree is an AVL tree that is safe for concurrent use. Lookups take a read
+// lock and may run in parallel while modifications take a write lock.
+type SyncTree[K, V any] struct {
+	mu   sync.RWMutex
+	tree *Tree[K, V]
+}
+
+// NewSyncTree creates a concurrency safe AVL tree using the supplied compare
+// function and tree options.
+func NewSyncTree[K, V any](compareKeys math.Comparator[K], options ...TreeOption[K, V]) *SyncTree[K, V] {
+	return &SyncTree[K, V]{tree: New(compareKeys, options...)}
+}
+
+// Add association between key and value to the tree. See Tree.Add.
+func (st *SyncTree[K, V]) Add(key K, value V) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.Add(key, value)
+}
+
+// Remove any association with key from tree. See Tree.Remove.
+func (st *SyncTree[K, V]) Remove(key K) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.Remove(key)
+}
+
+// Find value associated with key. See Tree.Find.
+func (st *SyncTree[K, V]) Find(key K) (V, bool) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Find(key)
+}
+
+// Contains reports whether the tree holds an association for key.
+func (st *SyncTree[K, V]) Contains(key K) bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Contains(key)
+}
+
+// Length returns the number of associations in the tree.
+func (st *SyncTree[K, V]) Length() int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Length()
+}
+
+// WithReadLock calls f with the underlying tree while holding a read lock. The
+// function must not modify the tree and must not keep any reference to it
+// after returning. Note that creating an iterator, which methods like Apply do,
+// links it to the tree and counts as a modification; use NewUntrackedIterator
+// instead.
+func (st *SyncTree[K, V]) WithReadLock(f func(tree *Tree[K, V])) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	f(st.tree)
+}
+
+// WithLock calls f with the underlying tree while holding a write lock. The
+// function may use any method of the tree but must not keep any reference to
+// it, including iterators, after returning.
+func (st *SyncTree[K, V]) WithLock(f func(tree *Tree[K, V])) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	f(st.tree)
+}
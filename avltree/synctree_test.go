@@ -0,0 +1,57 @@
+package avltree_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/johan-bolmsjo/gods/v2/avltree"
+	"github.com/johan-bolmsjo/gods/v2/math"
+)
+
+// Concurrent use of a sync tree should be safe (run with -race).
+func TestSyncTree(t *testing.T) {
+	st := avltree.NewSyncTree[keyType, valType](math.CompareOrdered[keyType])
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				k := keyType(g*100 + i)
+				st.Add(k, valType(k))
+				if i%2 == 1 {
+					st.Remove(k)
+				}
+			}
+		}(g)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				k := keyType(g*100 + i)
+				if v, ok := st.Find(k); ok && keyType(v) != k {
+					t.Errorf("st.Find(%d) = %v; want %v", k, v, k)
+				}
+				st.Contains(k)
+				st.WithReadLock(func(tree *treeType) {
+					iter := tree.NewUntrackedIterator()
+					for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
+						if keyType(v) != k {
+							t.Errorf("iter.Next() = %v,%v; want equal key and value", k, v)
+						}
+					}
+				})
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if got, want := st.Length(), 200; got != want {
+		t.Fatalf("st.Length() = %d; want %d", got, want)
+	}
+	st.WithLock(func(tree *treeType) {
+		if balanced, sorted := tree.Validate(); !balanced || !sorted {
+			t.Fatalf("tree.Validate() = %v, %v; want true, true", balanced, sorted)
+		}
+	})
+}
@@ -1,5 +1,10 @@
 package iter
 
+import (
+	"context"
+	"time"
+)
+
 // Iterator produce values of type T.
 type Iterator[T any] interface {
 	// Next returns the next value from the iterator and true if valid
@@ -7,12 +12,24 @@ type Iterator[T any] interface {
 	Next() (T, bool)
 }
 
+// FallibleIterator is an iterator that may fail to produce values. Next
+// reports false both when exhausted and on failure; Err tells them apart.
+type FallibleIterator[T any] interface {
+	Iterator[T]
+	// Err returns the error that stopped the iterator or nil if it's not
+	// stopped or was exhausted.
+	Err() error
+}
+
 // Scanner provides an API that is ergonomic with Go's limited form of while
 // loop. Use the Scan method as the termination clause and the Result method in
 // the loop body.
 type Scanner[T any] struct {
-	t T
-	g Iterator[T]
+	t      T
+	g      Iterator[T]
+	p      T    // Value fetched by Peek
+	pOK    bool // Peek fetched a valid value
+	peeked bool // Peek fetched a value not yet consumed by Scan
 }
 
 // NewScanner creates a scanner that fetch values from the given iterator.
@@ -23,15 +40,42 @@ func NewScanner[T any](g Iterator[T]) *Scanner[T] {
 // Scan gets the next item from its iterator and stores it for later retrieval.
 // Reports weather the iterator produced output or not.
 func (s *Scanner[T]) Scan() (ok bool) {
+	if s.peeked {
+		var zero T
+		s.t, ok = s.p, s.pOK
+		s.p, s.peeked = zero, false
+		return
+	}
 	s.t, ok = s.g.Next()
 	return
 }
 
+// Peek returns the value that the next Scan operation will get without
+// consuming it. Reports weather the iterator produced output or not. The
+// result of the last Scan operation is not affected.
+func (s *Scanner[T]) Peek() (T, bool) {
+	if !s.peeked {
+		s.p, s.pOK = s.g.Next()
+		s.peeked = true
+	}
+	return s.p, s.pOK
+}
+
 // Result of the last successful Scan operation.
 func (s *Scanner[T]) Result() T {
 	return s.t
 }
 
+// Err returns the error that stopped the iterator of the scanner after Scan
+// reported false. Nil is returned if the iterator was exhausted or if it
+// doesn't implement FallibleIterator.
+func (s *Scanner[T]) Err() error {
+	if f, ok := s.g.(FallibleIterator[T]); ok {
+		return f.Err()
+	}
+	return nil
+}
+
 // PairIterator produce pairs of values of type T and U.
 type PairIterator[T, U any] interface {
 	Next() (T, U, bool)
@@ -62,3 +106,255 @@ func (s *PairScanner[T, U]) Scan() (ok bool) {
 func (s *PairScanner[T, U]) Result() (T, U) {
 	return s.t, s.u
 }
+
+// GroupConsecutive partitions values from the given iterator into groups of
+// consecutive values sharing the same group key and calls f once per group.
+// The members iterator passed to f produces the values of the current group
+// only; values not consumed by f are skipped. A group key that reappears after
+// a different group key starts a new group. No values are buffered besides
+// the one that ends a group.
+func GroupConsecutive[T any, G comparable](it Iterator[T], groupKey func(T) G, f func(group G, members Iterator[T])) {
+	t, ok := it.Next()
+	for ok {
+		g := &groupIterator[T, G]{src: it, groupKey: groupKey, group: groupKey(t), t: t, buffered: true}
+		f(g.group, g)
+		for _, more := g.Next(); more; _, more = g.Next() {
+			// Skip remaining group members
+		}
+		t, ok = g.t, g.following
+	}
+}
+
+type groupIterator[T any, G comparable] struct {
+	src       Iterator[T]
+	groupKey  func(T) G
+	group     G
+	t         T    // Buffered value
+	buffered  bool // Buffered value is a member of the group
+	done      bool // Group is exhausted
+	following bool // Buffered value starts the following group
+}
+
+func (g *groupIterator[T, G]) Next() (T, bool) {
+	var zero T
+	if g.done {
+		return zero, false
+	}
+	if g.buffered {
+		g.buffered = false
+		return g.t, true
+	}
+
+	t, ok := g.src.Next()
+	if ok && g.groupKey(t) == g.group {
+		return t, true
+	}
+	g.t, g.done, g.following = t, true, ok
+	return zero, false
+}
+
+// WithDeadline returns an iterator that produce values from the given iterator
+// until the deadline has passed. The clock is checked on each call to Next and
+// no further values are produced once it reports a time after the deadline.
+// Callers should expect partial results.
+func WithDeadline[T any](it Iterator[T], deadline time.Time) Iterator[T] {
+	return &deadlineIterator[T]{src: it, deadline: deadline}
+}
+
+type deadlineIterator[T any] struct {
+	src      Iterator[T]
+	deadline time.Time
+}
+
+func (d *deadlineIterator[T]) Next() (T, bool) {
+	if time.Now().After(d.deadline) {
+		var zero T
+		return zero, false
+	}
+	return d.src.Next()
+}
+
+// WithContext returns an iterator that produce values from the given iterator
+// until the context is done. The context is checked on each call to Next.
+// Callers should expect partial results and may inspect ctx.Err() to tell
+// cancellation from exhaustion.
+func WithContext[T any](ctx context.Context, it Iterator[T]) Iterator[T] {
+	return &contextIterator[T]{src: it, ctx: ctx}
+}
+
+type contextIterator[T any] struct {
+	src Iterator[T]
+	ctx context.Context
+}
+
+func (c *contextIterator[T]) Next() (T, bool) {
+	if c.ctx.Err() != nil {
+		var zero T
+		return zero, false
+	}
+	return c.src.Next()
+}
+
+// ReplayIterator produce values buffered from another iterator. It can be reset
+// to produce the same values again any number of times.
+type ReplayIterator[T any] struct {
+	values []T
+	pos    int
+}
+
+// Buffer drains the given iterator and returns an iterator that replays its
+// values. All values are held in memory for as long as the replay iterator is
+// referenced.
+func Buffer[T any](it Iterator[T]) *ReplayIterator[T] {
+	return &ReplayIterator[T]{values: Collect(it)}
+}
+
+// Next returns the next buffered value and true if valid output was produced.
+func (r *ReplayIterator[T]) Next() (T, bool) {
+	if r.pos < len(r.values) {
+		r.pos++
+		return r.values[r.pos-1], true
+	}
+	var zero T
+	return zero, false
+}
+
+// Reset rewinds the iterator to produce the buffered values from the start.
+func (r *ReplayIterator[T]) Reset() {
+	r.pos = 0
+}
+
+// Map returns an iterator that produce the values of the given iterator
+// transformed by f. The function is called lazily as values are produced.
+func Map[T, U any](it Iterator[T], f func(T) U) Iterator[U] {
+	return &mapIterator[T, U]{src: it, f: f}
+}
+
+type mapIterator[T, U any] struct {
+	src Iterator[T]
+	f   func(T) U
+}
+
+func (m *mapIterator[T, U]) Next() (U, bool) {
+	t, ok := m.src.Next()
+	if !ok {
+		var zero U
+		return zero, false
+	}
+	return m.f(t), true
+}
+
+// Filter returns an iterator that produce the values of the given iterator for
+// which pred reports true.
+func Filter[T any](it Iterator[T], pred func(T) bool) Iterator[T] {
+	return &filterIterator[T]{src: it, pred: pred}
+}
+
+type filterIterator[T any] struct {
+	src  Iterator[T]
+	pred func(T) bool
+}
+
+func (f *filterIterator[T]) Next() (T, bool) {
+	for {
+		t, ok := f.src.Next()
+		if !ok || f.pred(t) {
+			return t, ok
+		}
+	}
+}
+
+// Collect drains the given iterator and returns its values in a slice. Nil is
+// returned if the iterator produced no values.
+func Collect[T any](it Iterator[T]) []T {
+	var values []T
+	for t, ok := it.Next(); ok; t, ok = it.Next() {
+		values = append(values, t)
+	}
+	return values
+}
+
+// CollectPairs drains the given pair iterator and returns the first and second
+// values of its pairs in two slices of equal length.
+func CollectPairs[T, U any](it PairIterator[T, U]) ([]T, []U) {
+	var ts []T
+	var us []U
+	for t, u, ok := it.Next(); ok; t, u, ok = it.Next() {
+		ts = append(ts, t)
+		us = append(us, u)
+	}
+	return ts, us
+}
+
+// Take returns an iterator that produce at most n values from the given
+// iterator. No values are produced if n is negative. The given iterator is not
+// advanced past the n:th value.
+func Take[T any](it Iterator[T], n int) Iterator[T] {
+	return &takeIterator[T]{src: it, n: n}
+}
+
+type takeIterator[T any] struct {
+	src Iterator[T]
+	n   int // Remaining values to produce
+}
+
+func (t *takeIterator[T]) Next() (T, bool) {
+	if t.n <= 0 {
+		var zero T
+		return zero, false
+	}
+	t.n--
+	return t.src.Next()
+}
+
+// Chain returns an iterator that produce the values of the given iterators in
+// order, exhausting each iterator before moving on to the next.
+func Chain[T any](its ...Iterator[T]) Iterator[T] {
+	return &chainIterator[T]{srcs: its}
+}
+
+type chainIterator[T any] struct {
+	srcs []Iterator[T] // Iterators not yet exhausted
+}
+
+func (c *chainIterator[T]) Next() (T, bool) {
+	for len(c.srcs) > 0 {
+		if t, ok := c.srcs[0].Next(); ok {
+			return t, true
+		}
+		c.srcs = c.srcs[1:]
+	}
+	var zero T
+	return zero, false
+}
+
+// Reduce drains the given iterator, calling f with the accumulated value and
+// each produced value in turn, and returns the final accumulated value. The
+// initial value init is returned if the iterator produced no values.
+func Reduce[T, A any](it Iterator[T], init A, f func(A, T) A) A {
+	acc := init
+	for t, ok := it.Next(); ok; t, ok = it.Next() {
+		acc = f(acc, t)
+	}
+	return acc
+}
+
+// Enumerate returns a pair iterator that produce the values of the given
+// iterator together with their index, starting at zero.
+func Enumerate[T any](it Iterator[T]) PairIterator[int, T] {
+	return &enumerateIterator[T]{src: it}
+}
+
+type enumerateIterator[T any] struct {
+	src   Iterator[T]
+	index int // Index of the next value
+}
+
+func (e *enumerateIterator[T]) Next() (int, T, bool) {
+	t, ok := e.src.Next()
+	if !ok {
+		return 0, t, false
+	}
+	e.index++
+	return e.index - 1, t, true
+}
@@ -1,8 +1,11 @@
 package iter_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/johan-bolmsjo/gods/v2/iter"
 )
@@ -61,3 +64,262 @@ func TestPairIterator(t *testing.T) {
 		t.Fatalf("got sequence %v; want %v", got, want)
 	}
 }
+
+func TestGroupConsecutive(t *testing.T) {
+	testData := []struct {
+		name  string
+		input SimpleIterator
+		take  int // Number of members to consume per group, < 0 for all
+		want  string
+	}{
+		{"Empty", SimpleIterator{}, -1, "[]"},
+		{"Single", SimpleIterator{1}, -1, "[0:[1]]"},
+		{"Groups", SimpleIterator{10, 11, 20, 30, 31, 32}, -1, "[1:[10 11] 2:[20] 3:[30 31 32]]"},
+		{"Reappearing", SimpleIterator{10, 20, 11}, -1, "[1:[10] 2:[20] 1:[11]]"},
+		{"PartiallyConsumed", SimpleIterator{10, 11, 20, 21}, 1, "[1:[10] 2:[20]]"},
+	}
+
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			var output []string
+			input := td.input
+			iter.GroupConsecutive[int](&input, func(v int) int { return v / 10 }, func(group int, members iter.Iterator[int]) {
+				var values []int
+				for len(values) != td.take {
+					v, ok := members.Next()
+					if !ok {
+						break
+					}
+					values = append(values, v)
+				}
+				output = append(output, fmt.Sprintf("%d:%v", group, values))
+			})
+			if got := fmt.Sprint(output); got != td.want {
+				t.Fatalf("got groups %v; want %v", got, td.want)
+			}
+		})
+	}
+}
+
+func TestWithDeadline(t *testing.T) {
+	testData := []struct {
+		name     string
+		deadline time.Time
+		want     string
+	}{
+		{"Passed", time.Now().Add(-time.Hour), "[]"},
+		{"Future", time.Now().Add(time.Hour), "[1 2 3]"},
+	}
+	for _, td := range testData {
+		t.Run(td.name, func(t *testing.T) {
+			simpleIter := SimpleIterator{1, 2, 3}
+			var output []int
+
+			scanner := iter.NewScanner(iter.WithDeadline[int](&simpleIter, td.deadline))
+			for scanner.Scan() {
+				output = append(output, scanner.Result())
+			}
+			if got := fmt.Sprint(output); got != td.want {
+				t.Fatalf("got sequence %v; want %v", got, td.want)
+			}
+		})
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	simpleIter := SimpleIterator{1, 2, 3}
+	var output []int
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scanner := iter.NewScanner(iter.WithContext[int](ctx, &simpleIter))
+	for scanner.Scan() {
+		output = append(output, scanner.Result())
+		if len(output) == 2 {
+			cancel()
+		}
+	}
+	if got, want := fmt.Sprint(output), "[1 2]"; got != want {
+		t.Fatalf("got sequence %v; want %v", got, want)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	simpleIter := SimpleIterator{1, 2, 3}
+	replay := iter.Buffer[int](&simpleIter)
+
+	// The source should be drained.
+	if v, ok := simpleIter.Next(); ok {
+		t.Fatalf("source produced %v after buffering", v)
+	}
+
+	for pass := 0; pass < 2; pass++ {
+		var output []int
+		scanner := iter.NewScanner[int](replay)
+		for scanner.Scan() {
+			output = append(output, scanner.Result())
+		}
+		if got, want := fmt.Sprint(output), "[1 2 3]"; got != want {
+			t.Fatalf("pass %d got sequence %v; want %v", pass, got, want)
+		}
+		replay.Reset()
+	}
+}
+
+func TestMap(t *testing.T) {
+	simpleIter := SimpleIterator{1, 2, 3}
+	var output []string
+
+	scanner := iter.NewScanner(iter.Map[int](&simpleIter, func(v int) string { return fmt.Sprint(v * 10) }))
+	for scanner.Scan() {
+		output = append(output, scanner.Result())
+	}
+	if got, want := fmt.Sprintf("%q", output), `["10" "20" "30"]`; got != want {
+		t.Fatalf("got sequence %v; want %v", got, want)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	simpleIter := SimpleIterator{1, 2, 3, 4, 5}
+	var output []int
+
+	scanner := iter.NewScanner(iter.Filter[int](&simpleIter, func(v int) bool { return v%2 == 1 }))
+	for scanner.Scan() {
+		output = append(output, scanner.Result())
+	}
+	if got, want := fmt.Sprint(output), "[1 3 5]"; got != want {
+		t.Fatalf("got sequence %v; want %v", got, want)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	simpleIter := SimpleIterator{1, 2, 3}
+	if got, want := fmt.Sprint(iter.Collect[int](&simpleIter)), "[1 2 3]"; got != want {
+		t.Fatalf("iter.Collect(...) = %v; want %v", got, want)
+	}
+	if got := iter.Collect[int](&simpleIter); got != nil {
+		t.Fatalf("iter.Collect(exhausted) = %v; want nil", got)
+	}
+
+	simplePairIter := SimplePairIterator{{1, "banana"}, {2, "apple"}}
+	keys, values := iter.CollectPairs[int, string](&simplePairIter)
+	if got, want := fmt.Sprint(keys, values), "[1 2] [banana apple]"; got != want {
+		t.Fatalf("iter.CollectPairs(...) = %v; want %v", got, want)
+	}
+}
+
+func TestTake(t *testing.T) {
+	testData := []struct {
+		n    int
+		want string
+	}{
+		{-1, "[]"},
+		{0, "[]"},
+		{2, "[1 2]"},
+		{3, "[1 2 3]"},
+		{4, "[1 2 3]"},
+	}
+	for _, td := range testData {
+		simpleIter := SimpleIterator{1, 2, 3}
+		if got := fmt.Sprint(iter.Collect(iter.Take[int](&simpleIter, td.n))); got != td.want {
+			t.Fatalf("iter.Take(..., %d) sequence %v; want %v", td.n, got, td.want)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	first, empty, last := SimpleIterator{1, 2}, SimpleIterator{}, SimpleIterator{3}
+	if got, want := fmt.Sprint(iter.Collect(iter.Chain[int](&first, &empty, &last))), "[1 2 3]"; got != want {
+		t.Fatalf("iter.Chain(...) sequence %v; want %v", got, want)
+	}
+	if got, want := fmt.Sprint(iter.Collect(iter.Chain[int]())), "[]"; got != want {
+		t.Fatalf("iter.Chain() sequence %v; want %v", got, want)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	sum := func(acc, v int) int { return acc + v }
+
+	simpleIter := SimpleIterator{1, 2, 3}
+	if got, want := iter.Reduce[int](&simpleIter, 10, sum), 16; got != want {
+		t.Fatalf("iter.Reduce(...) = %d; want %d", got, want)
+	}
+	if got, want := iter.Reduce[int](&simpleIter, 10, sum), 10; got != want {
+		t.Fatalf("iter.Reduce(exhausted) = %d; want %d", got, want)
+	}
+}
+
+type FallibleIterator struct {
+	SimpleIterator
+	err error // Error reported when exhausted
+}
+
+func (iter *FallibleIterator) Err() error {
+	if len(iter.SimpleIterator) == 0 {
+		return iter.err
+	}
+	return nil
+}
+
+func TestScannerErr(t *testing.T) {
+	errFailed := errors.New("failed")
+	fallibleIter := FallibleIterator{SimpleIterator{1, 2}, errFailed}
+	var output []int
+
+	scanner := iter.NewScanner[int](&fallibleIter)
+	for scanner.Scan() {
+		output = append(output, scanner.Result())
+	}
+	if got, want := fmt.Sprint(output), "[1 2]"; got != want {
+		t.Fatalf("got sequence %v; want %v", got, want)
+	}
+	if got := scanner.Err(); got != errFailed {
+		t.Fatalf("scanner.Err() = %v; want %v", got, errFailed)
+	}
+
+	simpleIter := SimpleIterator{1}
+	scanner = iter.NewScanner[int](&simpleIter)
+	for scanner.Scan() {
+	}
+	if got := scanner.Err(); got != nil {
+		t.Fatalf("scanner.Err() = %v; want nil", got)
+	}
+}
+
+func TestScannerPeek(t *testing.T) {
+	simpleIter := SimpleIterator{1, 2}
+	scanner := iter.NewScanner[int](&simpleIter)
+
+	for i, want := range []int{1, 1} {
+		if got, ok := scanner.Peek(); !ok || got != want {
+			t.Fatalf("scanner.Peek() #%d = %v, %v; want %v, true", i, got, ok, want)
+		}
+	}
+	if !scanner.Scan() || scanner.Result() != 1 {
+		t.Fatalf("scanner.Scan() did not produce 1 after peeking")
+	}
+	if got, ok := scanner.Peek(); !ok || got != 2 {
+		t.Fatalf("scanner.Peek() = %v, %v; want 2, true", got, ok)
+	}
+	if got := scanner.Result(); got != 1 {
+		t.Fatalf("scanner.Result() = %v after peeking; want 1", got)
+	}
+	if !scanner.Scan() || scanner.Result() != 2 {
+		t.Fatalf("scanner.Scan() did not produce 2 after peeking")
+	}
+	if _, ok := scanner.Peek(); ok {
+		t.Fatalf("scanner.Peek() = _, true; want _, false")
+	}
+	if scanner.Scan() {
+		t.Fatalf("scanner.Scan() = true; want false")
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	simpleIter := SimpleIterator{10, 20, 30}
+	indices, values := iter.CollectPairs(iter.Enumerate[int](&simpleIter))
+	if got, want := fmt.Sprint(indices, values), "[0 1 2] [10 20 30]"; got != want {
+		t.Fatalf("iter.Enumerate(...) = %v; want %v", got, want)
+	}
+}
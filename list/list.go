@@ -39,6 +39,86 @@ func (node *Node[T]) LinkPrev(other *Node[T]) {
 	node.prev = t
 }
 
+// SpliceBefore moves all nodes of the list anchored at head to the position
+// immediately previous to mark, preserving their order. The head node itself is
+// not moved and becomes an empty list. Nothing is moved if the list anchored at
+// head is empty. The mark node must not be a member of the list anchored at
+// head.
+func (mark *Node[T]) SpliceBefore(head *Node[T]) {
+	first, last := head.next, head.prev
+	if first == head {
+		return
+	}
+	head.next, head.prev = head, head
+
+	prev := mark.prev
+	prev.next = first
+	first.prev = prev
+	last.next = mark
+	mark.prev = last
+}
+
+// SpliceNext moves the nodes from first through last, which must be linked in
+// that order in the same list, to the position immediately next to mark,
+// preserving their order. The first and last nodes may be the same node. The
+// mark node must not be one of the moved nodes.
+func (mark *Node[T]) SpliceNext(first, last *Node[T]) {
+	first.prev.next = last.next
+	last.next.prev = first.prev
+
+	next := mark.next
+	mark.next = first
+	first.prev = mark
+	last.next = next
+	next.prev = last
+}
+
+// MoveBefore moves node from its current list, if any, to the position
+// immediately previous to mark. Nothing is done if node is mark.
+func (node *Node[T]) MoveBefore(mark *Node[T]) {
+	if node == mark {
+		return
+	}
+	node.next.prev = node.prev
+	node.prev.next = node.next
+
+	prev := mark.prev
+	node.prev, node.next = prev, mark
+	prev.next = node
+	mark.prev = node
+}
+
+// MoveAfter moves node from its current list, if any, to the position
+// immediately next to mark. Nothing is done if node is mark.
+func (node *Node[T]) MoveAfter(mark *Node[T]) {
+	if node == mark {
+		return
+	}
+	node.next.prev = node.prev
+	node.prev.next = node.next
+
+	next := mark.next
+	node.prev, node.next = mark, next
+	next.prev = node
+	mark.next = node
+}
+
+// Swap exchanges the positions of the linked nodes a and b, which may be
+// members of different lists. Nothing is done if a is b.
+func Swap[T any](a, b *Node[T]) {
+	switch {
+	case a == b:
+	case a.next == b:
+		b.MoveBefore(a)
+	case b.next == a:
+		a.MoveBefore(b)
+	default:
+		prev := a.prev
+		a.MoveAfter(b)
+		b.MoveAfter(prev)
+	}
+}
+
 // Unlink removes node from its list. It's safe to unlink unlinked nodes.
 func (node *Node[T]) Unlink() {
 	node.next.prev = node.prev
@@ -61,3 +141,108 @@ func (node *Node[T]) Prev() *Node[T] {
 func (node *Node[T]) IsLinked() bool {
 	return node.next != node
 }
+
+// ForEach calls f for each node of the list anchored at head in forward order,
+// stopping early if f returns false. The head node itself is not visited. The
+// next node is read before f is called so f may unlink the node it's called
+// with.
+func (head *Node[T]) ForEach(f func(*Node[T]) bool) {
+	for node := head.next; node != head; {
+		next := node.next
+		if !f(node) {
+			return
+		}
+		node = next
+	}
+}
+
+// ForEachReverse is like ForEach but visits the nodes in reverse order.
+func (head *Node[T]) ForEachReverse(f func(*Node[T]) bool) {
+	for node := head.prev; node != head; {
+		prev := node.prev
+		if !f(node) {
+			return
+		}
+		node = prev
+	}
+}
+
+// Count returns the number of nodes in the list anchored at head whose value
+// satisfies pred. The head node itself is not counted. All nodes are counted if
+// pred is nil.
+func Count[T any](head *Node[T], pred func(T) bool) int {
+	n := 0
+	for node := head.next; node != head; node = node.next {
+		if pred == nil || pred(node.Value) {
+			n++
+		}
+	}
+	return n
+}
+
+// List is a list head that keeps track of the number of nodes linked to it. The
+// length is only maintained when nodes are linked and unlinked using the
+// methods of List, not when using the methods of Node directly. The zero value
+// is an empty list ready to use. A List must not be copied after first use.
+type List[T any] struct {
+	head   Node[T]
+	length int
+}
+
+// NewList returns an empty list.
+func NewList[T any]() *List[T] {
+	return new(List[T]).lazyInit()
+}
+
+func (l *List[T]) lazyInit() *List[T] {
+	if l.head.next == nil {
+		l.head.InitLinks()
+	}
+	return l
+}
+
+// Head returns the sentinel node of the list. It may be used to iterate over
+// the list but nodes must not be linked to or unlinked from it directly.
+func (l *List[T]) Head() *Node[T] {
+	return &l.lazyInit().head
+}
+
+// Len returns the number of nodes in the list.
+func (l *List[T]) Len() int {
+	return l.length
+}
+
+// LinkNext links node first in the list. The node must not be linked to any
+// other node. A zero value node is initialized as by InitLinks.
+func (l *List[T]) LinkNext(node *Node[T]) {
+	l.lazyInit().head.LinkNext(unlinked(node))
+	l.length++
+}
+
+// LinkPrev links node last in the list. The node must not be linked to any
+// other node. A zero value node is initialized as by InitLinks.
+func (l *List[T]) LinkPrev(node *Node[T]) {
+	l.lazyInit().head.LinkPrev(unlinked(node))
+	l.length++
+}
+
+// Unlink removes node from the list. The node must be a member of the list or
+// be unlinked, in which case nothing is done. Zero value nodes are unlinked.
+func (l *List[T]) Unlink(node *Node[T]) {
+	if node.next != nil && node.IsLinked() {
+		node.Unlink()
+		l.length--
+	}
+}
+
+// Return node after initializing its links if it's a zero value node. Panics
+// if node is linked to another node as the length of a list would be off.
+func unlinked[T any](node *Node[T]) *Node[T] {
+	if node.next == nil {
+		return node.InitLinks()
+	}
+	if node.IsLinked() {
+		panic("list: linking a node that is linked to other nodes to a tracked list")
+	}
+	return node
+}
@@ -87,6 +87,220 @@ func TestLinkPrev(t *testing.T) {
 	})
 }
 
+func TestSpliceBefore(t *testing.T) {
+	var nodes [6]*list.Node[int]
+	for i := range nodes {
+		nodes[i] = list.New[int]()
+		nodes[i].Value = i
+	}
+
+	// List [0, 1, 2] with node 2 as the mark and source list [3, 4, 5]
+	head1, mark := nodes[0], nodes[2]
+	head1.LinkPrev(nodes[1])
+	head1.LinkPrev(nodes[2])
+	head2 := nodes[3]
+	head2.LinkPrev(nodes[4])
+	head2.LinkPrev(nodes[5])
+
+	// Expected list node order [0, 1, 4, 5, 2]
+	mark.SpliceBefore(head2)
+	checkLinks(t, head1, []link[int]{
+		{nodes[1], nodes[2]},
+		{nodes[4], nodes[0]},
+		{nodes[5], nodes[1]},
+		{nodes[2], nodes[4]},
+		{nodes[0], nodes[5]},
+	})
+	checkLinks(t, head2, []link[int]{
+		{head2, head2},
+	})
+
+	// Splicing an empty list should have no effect.
+	mark.SpliceBefore(head2)
+	checkLinks(t, head1, []link[int]{
+		{nodes[1], nodes[2]},
+		{nodes[4], nodes[0]},
+		{nodes[5], nodes[1]},
+		{nodes[2], nodes[4]},
+		{nodes[0], nodes[5]},
+	})
+}
+
+func TestSpliceNext(t *testing.T) {
+	var nodes [7]*list.Node[int]
+	for i := range nodes {
+		nodes[i] = list.New[int]()
+		nodes[i].Value = i
+	}
+
+	// List [0, 1, 2, 3] and source list [4, 5, 6]
+	head1 := nodes[0]
+	for _, node := range nodes[1:4] {
+		head1.LinkPrev(node)
+	}
+	head2 := nodes[4]
+	head2.LinkPrev(nodes[5])
+	head2.LinkPrev(nodes[6])
+
+	// Expected list node order [0, 1, 5, 6, 2, 3] and [4]
+	nodes[1].SpliceNext(nodes[5], nodes[6])
+	checkLinks(t, head1, []link[int]{
+		{nodes[1], nodes[3]},
+		{nodes[5], nodes[0]},
+		{nodes[6], nodes[1]},
+		{nodes[2], nodes[5]},
+		{nodes[3], nodes[6]},
+		{nodes[0], nodes[2]},
+	})
+	checkLinks(t, head2, []link[int]{
+		{head2, head2},
+	})
+
+	// Move a run within the same list and a single node.
+	// Expected list node order [0, 2, 3, 1, 5, 6] followed by [0, 6, 2, 3, 1, 5]
+	nodes[3].SpliceNext(nodes[1], nodes[6])
+	nodes[0].SpliceNext(nodes[6], nodes[6])
+	checkLinks(t, head1, []link[int]{
+		{nodes[6], nodes[5]},
+		{nodes[2], nodes[0]},
+		{nodes[3], nodes[6]},
+		{nodes[1], nodes[2]},
+		{nodes[5], nodes[3]},
+		{nodes[0], nodes[1]},
+	})
+}
+
+func TestMoveBefore(t *testing.T) {
+	var nodes [5]*list.Node[int]
+	for i := range nodes {
+		nodes[i] = list.New[int]()
+		nodes[i].Value = i
+	}
+
+	// List [0, 1, 2, 3] and the unlinked node 4
+	head := nodes[0]
+	head.LinkPrev(nodes[1])
+	head.LinkPrev(nodes[2])
+	head.LinkPrev(nodes[3])
+
+	// Expected list node order [0, 3, 1, 2]
+	nodes[3].MoveBefore(nodes[1])
+	checkLinks(t, head, []link[int]{
+		{nodes[3], nodes[2]},
+		{nodes[1], nodes[0]},
+		{nodes[2], nodes[3]},
+		{nodes[0], nodes[1]},
+	})
+
+	// Moving a node before its next node or itself should have no effect.
+	nodes[3].MoveBefore(nodes[1])
+	nodes[3].MoveBefore(nodes[3])
+	checkLinks(t, head, []link[int]{
+		{nodes[3], nodes[2]},
+		{nodes[1], nodes[0]},
+		{nodes[2], nodes[3]},
+		{nodes[0], nodes[1]},
+	})
+
+	// Expected list node order [0, 3, 1, 4, 2]
+	nodes[4].MoveBefore(nodes[2])
+	checkLinks(t, head, []link[int]{
+		{nodes[3], nodes[2]},
+		{nodes[1], nodes[0]},
+		{nodes[4], nodes[3]},
+		{nodes[2], nodes[1]},
+		{nodes[0], nodes[4]},
+	})
+}
+
+func TestMoveAfter(t *testing.T) {
+	var nodes [5]*list.Node[int]
+	for i := range nodes {
+		nodes[i] = list.New[int]()
+		nodes[i].Value = i
+	}
+
+	// List [0, 1, 2, 3] and the unlinked node 4
+	head := nodes[0]
+	head.LinkPrev(nodes[1])
+	head.LinkPrev(nodes[2])
+	head.LinkPrev(nodes[3])
+
+	// Expected list node order [0, 2, 3, 1]
+	nodes[1].MoveAfter(nodes[3])
+	checkLinks(t, head, []link[int]{
+		{nodes[2], nodes[1]},
+		{nodes[3], nodes[0]},
+		{nodes[1], nodes[2]},
+		{nodes[0], nodes[3]},
+	})
+
+	// Moving a node after its previous node or itself should have no effect.
+	nodes[1].MoveAfter(nodes[3])
+	nodes[1].MoveAfter(nodes[1])
+	checkLinks(t, head, []link[int]{
+		{nodes[2], nodes[1]},
+		{nodes[3], nodes[0]},
+		{nodes[1], nodes[2]},
+		{nodes[0], nodes[3]},
+	})
+
+	// Expected list node order [0, 4, 2, 3, 1]
+	nodes[4].MoveAfter(head)
+	checkLinks(t, head, []link[int]{
+		{nodes[4], nodes[1]},
+		{nodes[2], nodes[0]},
+		{nodes[3], nodes[4]},
+		{nodes[1], nodes[2]},
+		{nodes[0], nodes[3]},
+	})
+}
+
+func TestSwap(t *testing.T) {
+	var nodes [5]*list.Node[int]
+	for i := range nodes {
+		nodes[i] = list.New[int]()
+		nodes[i].Value = i
+	}
+
+	// List [0, 1, 2, 3, 4]
+	head := nodes[0]
+	for _, node := range nodes[1:] {
+		head.LinkPrev(node)
+	}
+
+	// Expected list node order [0, 3, 2, 1, 4]
+	list.Swap(nodes[1], nodes[3])
+	checkLinks(t, head, []link[int]{
+		{nodes[3], nodes[4]},
+		{nodes[2], nodes[0]},
+		{nodes[1], nodes[3]},
+		{nodes[4], nodes[2]},
+		{nodes[0], nodes[1]},
+	})
+
+	// Expected list node order [0, 2, 3, 1, 4] followed by [0, 2, 1, 3, 4]
+	list.Swap(nodes[3], nodes[2])
+	list.Swap(nodes[1], nodes[3])
+	checkLinks(t, head, []link[int]{
+		{nodes[2], nodes[4]},
+		{nodes[1], nodes[0]},
+		{nodes[3], nodes[2]},
+		{nodes[4], nodes[1]},
+		{nodes[0], nodes[3]},
+	})
+
+	// Swapping a node with itself should have no effect.
+	list.Swap(nodes[4], nodes[4])
+	checkLinks(t, head, []link[int]{
+		{nodes[2], nodes[4]},
+		{nodes[1], nodes[0]},
+		{nodes[3], nodes[2]},
+		{nodes[4], nodes[1]},
+		{nodes[0], nodes[3]},
+	})
+}
+
 func TestUnlink(t *testing.T) {
 	var nodes [3]*list.Node[int]
 	for i := range nodes {
@@ -146,3 +360,123 @@ func TestIsLinked(t *testing.T) {
 		t.Fatalf("e1.IsLinked() = false; want true")
 	}
 }
+
+func TestCount(t *testing.T) {
+	head := list.New[int]()
+	isEven := func(v int) bool { return v%2 == 0 }
+
+	if got, want := list.Count(head, nil), 0; got != want {
+		t.Fatalf("list.Count(empty, nil) = %d; want %d", got, want)
+	}
+	if got, want := list.Count(head, isEven), 0; got != want {
+		t.Fatalf("list.Count(empty, isEven) = %d; want %d", got, want)
+	}
+
+	for i := 0; i < 5; i++ {
+		node := list.New[int]()
+		node.Value = i
+		head.LinkPrev(node)
+	}
+
+	if got, want := list.Count(head, nil), 5; got != want {
+		t.Fatalf("list.Count(head, nil) = %d; want %d", got, want)
+	}
+	if got, want := list.Count(head, isEven), 3; got != want {
+		t.Fatalf("list.Count(head, isEven) = %d; want %d", got, want)
+	}
+}
+
+func TestList(t *testing.T) {
+	var l list.List[int]
+	if got, want := l.Len(), 0; got != want {
+		t.Fatalf("l.Len() = %d; want %d", got, want)
+	}
+	if l.Head().IsLinked() {
+		t.Fatalf("l.Head().IsLinked() = true; want false")
+	}
+
+	var nodes [3]*list.Node[int]
+	for i := range nodes {
+		nodes[i] = list.New[int]()
+		nodes[i].Value = i
+	}
+	l.LinkPrev(nodes[1])
+	l.LinkPrev(nodes[2])
+	l.LinkNext(nodes[0])
+	if got, want := l.Len(), 3; got != want {
+		t.Fatalf("l.Len() = %d; want %d", got, want)
+	}
+
+	// Expected list node order [head, 0, 1, 2]
+	head := l.Head()
+	checkLinks(t, head, []link[int]{
+		{nodes[0], nodes[2]},
+		{nodes[1], head},
+		{nodes[2], nodes[0]},
+		{head, nodes[1]},
+	})
+
+	// Unlinking an unlinked node should have no effect on the length.
+	l.Unlink(nodes[1])
+	l.Unlink(nodes[1])
+	if got, want := l.Len(), 2; got != want {
+		t.Fatalf("l.Len() = %d; want %d", got, want)
+	}
+	if got, want := list.Count(head, nil), 2; got != want {
+		t.Fatalf("list.Count(l.Head(), nil) = %d; want %d", got, want)
+	}
+
+	// Zero value nodes should be accepted as unlinked.
+	var zero list.Node[int]
+	l.Unlink(&zero)
+	l.LinkPrev(&zero)
+	if got, want := l.Len(), 3; got != want {
+		t.Fatalf("l.Len() = %d; want %d", got, want)
+	}
+	if got, want := head.Prev(), &zero; got != want {
+		t.Fatalf("l.Head().Prev() = %p; want %p", got, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("l.LinkNext(linked) did not panic")
+		}
+	}()
+	l.LinkNext(nodes[0])
+}
+
+func TestForEach(t *testing.T) {
+	head := list.New[int]()
+	for i := 0; i < 5; i++ {
+		node := list.New[int]()
+		node.Value = i
+		head.LinkPrev(node)
+	}
+
+	// Unlinking the visited node must not stop the iteration.
+	var got []int
+	head.ForEach(func(node *list.Node[int]) bool {
+		got = append(got, node.Value)
+		if node.Value%2 == 1 {
+			node.Unlink()
+		}
+		return true
+	})
+	if want := []int{0, 1, 2, 3, 4}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("head.ForEach(...) visited %v; want %v", got, want)
+	}
+
+	got = nil
+	head.ForEachReverse(func(node *list.Node[int]) bool {
+		got = append(got, node.Value)
+		return node.Value != 2
+	})
+	if want := []int{4, 2}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("head.ForEachReverse(...) visited %v; want %v", got, want)
+	}
+
+	list.New[int]().ForEach(func(node *list.Node[int]) bool {
+		t.Fatalf("empty.ForEach(...) visited %v", node.Value)
+		return true
+	})
+}
@@ -0,0 +1,14 @@
+//go:build go1.23
+
+package list
+
+import "iter"
+
+// All returns a range-over-func iterator over the nodes of the list anchored at
+// head in forward order. The head node itself is not yielded. The next node is
+// read before a node is yielded so the loop body may unlink it.
+func (head *Node[T]) All() iter.Seq[*Node[T]] {
+	return func(yield func(*Node[T]) bool) {
+		head.ForEach(yield)
+	}
+}
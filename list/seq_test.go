@@ -0,0 +1,34 @@
+//go:build go1.23
+
+package list_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/johan-bolmsjo/gods/v2/list"
+)
+
+func TestAll(t *testing.T) {
+	head := list.New[int]()
+	for i := 0; i < 5; i++ {
+		node := list.New[int]()
+		node.Value = i
+		head.LinkPrev(node)
+	}
+
+	var got []int
+	for node := range head.All() {
+		got = append(got, node.Value)
+		node.Unlink()
+		if node.Value == 3 {
+			break
+		}
+	}
+	if want := []int{0, 1, 2, 3}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("head.All() yielded %v; want %v", got, want)
+	}
+	if got, want := list.Count(head, nil), 1; got != want {
+		t.Fatalf("list.Count(head, nil) = %d; want %d", got, want)
+	}
+}
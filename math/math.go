@@ -1,6 +1,10 @@
 package math
 
-import "constraints"
+import (
+	"constraints"
+	"sort"
+	"time"
+)
 
 // Compare two items and return a value less than, equal to, or greater than
 // zero if lhs is found, respectively, to be less than, to match, or be greater
@@ -19,6 +23,49 @@ func CompareOrdered[T constraints.Ordered](lhs, rhs T) int {
 	return 1
 }
 
+// CompareOrderedDesc compares two values satisfying constraints.Ordered in
+// descending order. It returns a value less than, equal to, or greater than zero
+// if lhs is found, respectively, to be greater than, to match, or be less than
+// rhs.
+func CompareOrderedDesc[T constraints.Ordered](lhs, rhs T) int {
+	return CompareOrdered(rhs, lhs)
+}
+
+// CompareTime compares two time instants and return a value less than, equal
+// to, or greater than zero if lhs is found, respectively, to be before, to be
+// equal to, or be after rhs.
+func CompareTime(lhs, rhs time.Time) int {
+	if lhs.Before(rhs) {
+		return -1
+	} else if lhs.After(rhs) {
+		return 1
+	}
+	return 0
+}
+
+// Reverse returns a comparator that orders values in the reverse order of c.
+// The arguments are swapped rather than the result negated, which would not
+// reverse a result of the lowest integer value.
+func Reverse[T any](c Comparator[T]) Comparator[T] {
+	return func(lhs, rhs T) int {
+		return c(rhs, lhs)
+	}
+}
+
+// Chain returns a comparator that applies the comparators in cs in order and
+// returns the first non-zero result. Zero is returned if all comparators report
+// equal, which is always the case if cs is empty.
+func Chain[T any](cs ...Comparator[T]) Comparator[T] {
+	return func(lhs, rhs T) int {
+		for _, c := range cs {
+			if r := c(lhs, rhs); r != 0 {
+				return r
+			}
+		}
+		return 0
+	}
+}
+
 // AbsSigned returns the absolute value of a signed integer value.
 func AbsSigned[T constraints.Signed](val T) T {
 	if val < 0 {
@@ -42,3 +89,26 @@ func MaxInteger[T constraints.Integer](lhs, rhs T) T {
 	}
 	return rhs
 }
+
+// CheckDistinct reports whether cmp considers any two of the given keys equal.
+// The keys are sorted (a copy, the input is unmodified) and the first key found
+// to compare equal to its predecessor is returned together with true. Among
+// equal keys, the returned key is one that appears later in the input than
+// another key it collides with. The zero value of K and false is returned if
+// all keys are distinct.
+func CheckDistinct[K any](cmp Comparator[K], keys []K) (K, bool) {
+	sorted := make([]K, len(keys))
+	copy(sorted, keys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return cmp(sorted[i], sorted[j]) < 0
+	})
+
+	for i := 1; i < len(sorted); i++ {
+		if cmp(sorted[i-1], sorted[i]) == 0 {
+			return sorted[i], true
+		}
+	}
+
+	var zero K
+	return zero, false
+}
@@ -1,7 +1,9 @@
 package math_test
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/johan-bolmsjo/gods/v2/math"
 )
@@ -19,6 +21,90 @@ func TestCompareOrdered(t *testing.T) {
 	}
 }
 
+func TestCompareOrderedDesc(t *testing.T) {
+	testData := [][3]int{
+		{-100, 100, 1},
+		{100, -100, -1},
+		{0, 0, 0},
+	}
+	for _, td := range testData {
+		if got, want := math.CompareOrderedDesc(td[0], td[1]), td[2]; got != want {
+			t.Fatalf("math.CompareOrderedDesc(%d, %d) = %d; want %d", td[0], td[1], got, want)
+		}
+	}
+}
+
+func TestCompareTime(t *testing.T) {
+	t0 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Nanosecond)
+	testData := []struct {
+		lhs, rhs time.Time
+		want     int
+	}{
+		{t0, t1, -1},
+		{t1, t0, 1},
+		{t0, t0, 0},
+		{t0, t0.In(time.FixedZone("UTC+1", 3600)), 0}, // Same instant in another location
+	}
+	for _, td := range testData {
+		if got := math.CompareTime(td.lhs, td.rhs); got != td.want {
+			t.Fatalf("math.CompareTime(%v, %v) = %d; want %d", td.lhs, td.rhs, got, td.want)
+		}
+	}
+}
+
+func TestReverse(t *testing.T) {
+	// A comparator returning the lowest int value must still be reversed.
+	minInt := -int(^uint(0)>>1) - 1
+	extreme := func(lhs, rhs int) int {
+		if lhs < rhs {
+			return minInt
+		}
+		return math.CompareOrdered(lhs, rhs)
+	}
+
+	testData := [][3]int{
+		{-100, 100, 1},
+		{100, -100, -1},
+		{0, 0, 0},
+	}
+	for _, td := range testData {
+		if got, want := math.Reverse(math.CompareOrdered[int])(td[0], td[1]), td[2]; got != want {
+			t.Fatalf("math.Reverse(math.CompareOrdered)(%d, %d) = %d; want %d", td[0], td[1], got, want)
+		}
+		if got, want := math.Reverse(extreme)(td[0], td[1]), td[2]; (got > 0) != (want > 0) || (got < 0) != (want < 0) {
+			t.Fatalf("math.Reverse(extreme)(%d, %d) = %d; want sign of %d", td[0], td[1], got, want)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	type pair struct{ a, b int }
+	byA := func(lhs, rhs pair) int { return math.CompareOrdered(lhs.a, rhs.a) }
+	byB := func(lhs, rhs pair) int { return math.CompareOrdered(lhs.b, rhs.b) }
+	cmp := math.Chain(byA, byB)
+
+	testData := []struct {
+		lhs, rhs pair
+		want     int
+	}{
+		{pair{1, 2}, pair{2, 1}, -1},
+		{pair{2, 1}, pair{1, 2}, 1},
+		{pair{1, 1}, pair{1, 2}, -1},
+		{pair{1, 2}, pair{1, 1}, 1},
+		{pair{1, 1}, pair{1, 1}, 0},
+	}
+	for _, td := range testData {
+		if got := cmp(td.lhs, td.rhs); got != td.want {
+			t.Fatalf("math.Chain(byA, byB)(%v, %v) = %d; want %d", td.lhs, td.rhs, got, td.want)
+		}
+	}
+
+	if got := math.Chain[pair]()(pair{1, 2}, pair{2, 1}); got != 0 {
+		t.Fatalf("math.Chain()(...) = %d; want 0", got)
+	}
+}
+
 func TestAbsSigned(t *testing.T) {
 	testData := [][2]int{
 		{-100, 100},
@@ -56,3 +142,31 @@ func TestMaxInteger(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckDistinct(t *testing.T) {
+	// Compare by absolute value to produce collisions between distinct ints.
+	compareAbs := func(lhs, rhs int) int {
+		return math.CompareOrdered(math.AbsSigned(lhs), math.AbsSigned(rhs))
+	}
+
+	testData := []struct {
+		keys []int
+		want string
+	}{
+		{nil, "0,false"},
+		{[]int{1}, "0,false"},
+		{[]int{3, 1, 2}, "0,false"},
+		{[]int{3, -1, 2, 1}, "1,true"},
+		{[]int{5, -2, 4, 2, -5}, "2,true"},
+	}
+	for _, td := range testData {
+		input := fmt.Sprint(td.keys)
+		k, ok := math.CheckDistinct(compareAbs, td.keys)
+		if got := fmt.Sprintf("%v,%v", k, ok); got != td.want {
+			t.Fatalf("math.CheckDistinct(%v) = %s; want %s", td.keys, got, td.want)
+		}
+		if got := fmt.Sprint(td.keys); got != input {
+			t.Fatalf("math.CheckDistinct modified input %s; got %s", input, got)
+		}
+	}
+}